@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Placeholders in the API URL template, replaced with URL-escaped values.
+const (
+	apiUsernamePlaceholder = "{username}"
+	apiHashPlaceholder     = "{hash}"
+)
+
+// apiRequest is the POST the API panel sends to a hosting panel's
+// set-password endpoint.
+type apiRequest struct {
+	URL  string
+	Body []byte // JSON apiPayload
+}
+
+// apiPayload is the JSON body of an apiRequest.
+type apiPayload struct {
+	Username string `json:"username"`
+	Mode     int    `json:"mode"`
+	Hash     string `json:"hash"`
+}
+
+// apiTimeout bounds a request so an unreachable panel doesn't leave the
+// button disabled indefinitely.
+const apiTimeout = 15 * time.Second
+
+// newAPIRequest fills in urlTemplate's placeholders and builds the JSON body.
+// The expanded URL must be absolute http or https, and a template that uses
+// {username} needs a non-empty username.
+func newAPIRequest(urlTemplate, username string, mode int, hash string) (apiRequest, error) {
+	urlTemplate = strings.TrimSpace(urlTemplate)
+	if urlTemplate == "" {
+		return apiRequest{}, fmt.Errorf("no URL template is set")
+	}
+	if username == "" && strings.Contains(urlTemplate, apiUsernamePlaceholder) {
+		return apiRequest{}, fmt.Errorf("the URL template uses %s but the entry has no username", apiUsernamePlaceholder)
+	}
+	expanded := strings.NewReplacer(
+		apiUsernamePlaceholder, url.PathEscape(username),
+		apiHashPlaceholder, url.QueryEscape(hash),
+	).Replace(urlTemplate)
+
+	u, err := url.Parse(expanded)
+	if err != nil {
+		return apiRequest{}, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return apiRequest{}, fmt.Errorf("URL must start with http:// or https://, got %q", urlTemplate)
+	}
+
+	body, err := json.Marshal(apiPayload{Username: username, Mode: mode, Hash: hash})
+	if err != nil {
+		return apiRequest{}, err
+	}
+	return apiRequest{URL: expanded, Body: body}, nil
+}
+
+// insecure reports whether the request would send the hash unencrypted.
+func (r apiRequest) insecure() bool {
+	return strings.HasPrefix(r.URL, "http://")
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// curl returns a curl command equivalent to sending r, for running the
+// request elsewhere or pasting into a ticket as a dry run.
+func (r apiRequest) curl() string {
+	return "curl -X POST -H 'Content-Type: application/json' --data " +
+		shellQuote(string(r.Body)) + " " + shellQuote(r.URL)
+}
+
+// sendAPIRequest POSTs r and returns the response status. A non-2xx status is
+// an error that includes the start of the response body, which is usually
+// where a panel explains what it didn't like.
+func sendAPIRequest(ctx context.Context, client *http.Client, r apiRequest) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, apiTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(r.Body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return resp.Status, fmt.Errorf("server answered %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return resp.Status, nil
+}
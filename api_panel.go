@@ -0,0 +1,110 @@
+//go:build !cli
+
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// prefAPIURLTemplate is the API panel's URL template. It is saved as typed,
+// so a template carrying an API key is stored like the remembered database
+// password.
+const prefAPIURLTemplate = "apiURLTemplate"
+
+// buildAPIPanel builds the optional panel that sends the generated hash to a
+// hosting panel's HTTP endpoint, or shows the equivalent curl command as a
+// dry run. generated returns the last generated hash and its account.
+func buildAPIPanel(w fyne.Window, statusLabel *widget.Label, prefs fyne.Preferences, generated func() historyEntry) fyne.CanvasObject {
+	urlEntry := widget.NewEntry()
+	urlEntry.SetPlaceHolder("https://panel.example.com/api/accounts/{username}/password")
+	urlEntry.SetText(prefs.String(prefAPIURLTemplate))
+	urlEntry.OnChanged = func(text string) { prefs.SetString(prefAPIURLTemplate, text) }
+
+	hint := widget.NewLabelWithStyle(tr("api.hint"), fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+	hint.Wrapping = fyne.TextWrapWord
+
+	// The dry run's request, shown read-only so it can be checked or copied.
+	preview := widget.NewMultiLineEntry()
+	preview.Wrapping = fyne.TextWrapBreak
+	preview.Disable()
+	preview.Hide()
+
+	// request builds the request for the last generated hash, reporting
+	// problems in the status bar.
+	request := func() (apiRequest, historyEntry, bool) {
+		entry := generated()
+		if entry.Hash == "" {
+			statusLabel.SetText(tr("db.generate_first"))
+			return apiRequest{}, entry, false
+		}
+		r, err := newAPIRequest(urlEntry.Text, entry.Username, entry.Mode, entry.Hash)
+		if err != nil {
+			statusLabel.SetText(tr("error", err))
+			return apiRequest{}, entry, false
+		}
+		return r, entry, true
+	}
+
+	dryRunButton := widget.NewButton(tr("api.dry_run"), func() {
+		r, _, ok := request()
+		if !ok {
+			return
+		}
+		preview.SetText("POST " + r.URL + "\nContent-Type: application/json\n\n" + string(r.Body))
+		preview.Show()
+		if r.insecure() {
+			statusLabel.SetText(tr("api.insecure"))
+		} else {
+			statusLabel.SetText(tr("api.dry_run_done"))
+		}
+	})
+
+	copyCurlButton := widget.NewButton(tr("api.copy_curl"), func() {
+		if r, _, ok := request(); ok {
+			copyToClipboard(w, statusLabel, prefs, r.curl())
+		}
+	})
+
+	var sendButton *widget.Button
+	sendButton = widget.NewButton(tr("api.send"), func() {
+		r, entry, ok := request()
+		if !ok {
+			return
+		}
+		message := tr("api.confirm", entry.Username, r.URL)
+		if r.insecure() {
+			message += "\n\n" + tr("api.insecure")
+		}
+		dialog.ShowConfirm(tr("api.send"), message, func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			sendButton.Disable()
+			statusLabel.SetText(tr("api.sending", r.URL))
+			go func() {
+				defer sendButton.Enable()
+				defer recoverPanic(showPanic(statusLabel, nil))
+				status, err := sendAPIRequest(context.Background(), http.DefaultClient, r)
+				auditAPIPost(r, entry.Username, entry.Hash, err)
+				if err != nil {
+					statusLabel.SetText(tr("error", err))
+					return
+				}
+				statusLabel.SetText(tr("api.sent", entry.Username, status))
+			}()
+		}, w)
+	})
+
+	return container.NewVBox(
+		hint,
+		widget.NewForm(widget.NewFormItem(tr("api.url"), urlEntry)),
+		container.NewHBox(dryRunButton, copyCurlButton, sendButton),
+		preview,
+	)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewAPIRequest(t *testing.T) {
+	hash := goldenVectors[14]
+	r, err := newAPIRequest(" https://panel.example.com/api/{username}/password?h={hash} ", "o'brien x", 14, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://panel.example.com/api/o%27brien%20x/password?h=%247%24"; !strings.HasPrefix(r.URL, want) {
+		t.Errorf("URL %q, want prefix %q", r.URL, want)
+	}
+	if strings.Contains(r.URL, "$") || r.insecure() {
+		t.Errorf("URL %q not escaped or reported insecure", r.URL)
+	}
+
+	var payload apiPayload
+	if err := json.Unmarshal(r.Body, &payload); err != nil || payload != (apiPayload{Username: "o'brien x", Mode: 14, Hash: hash}) {
+		t.Errorf("body %s: %v", r.Body, err)
+	}
+
+	for _, bad := range []string{"", "panel.example.com/{username}", "ftp://panel.example.com/", "https:///path"} {
+		if _, err := newAPIRequest(bad, "bob", 1, goldenVectors[1]); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+	if _, err := newAPIRequest("https://panel.example.com/api/{username}", "", 1, goldenVectors[1]); err == nil {
+		t.Error("empty username in a {username} template: expected an error")
+	}
+	if _, err := newAPIRequest("https://panel.example.com/api/set", "", 1, goldenVectors[1]); err != nil {
+		t.Errorf("template without {username}: %v", err)
+	}
+	if r, _ := newAPIRequest("http://10.0.0.5/set", "bob", 1, goldenVectors[1]); !r.insecure() {
+		t.Error("http URL not reported insecure")
+	}
+}
+
+func TestAPIRequestCurl(t *testing.T) {
+	r := apiRequest{URL: "https://panel.example.com/a?b=1&c=2", Body: []byte(`{"username":"o'brien"}`)}
+	want := `curl -X POST -H 'Content-Type: application/json' --data '{"username":"o'\''brien"}' 'https://panel.example.com/a?b=1&c=2'`
+	if got := r.curl(); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestSendAPIRequest(t *testing.T) {
+	var gotBody, gotType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		gotBody, gotType = string(body), req.Header.Get("Content-Type")
+		if req.Method != http.MethodPost || strings.HasSuffix(req.URL.Path, "/nobody") {
+			http.Error(w, "no such account", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	r, err := newAPIRequest(server.URL+"/accounts/{username}", goldenUsername, 14, goldenVectors[14])
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := sendAPIRequest(context.Background(), server.Client(), r)
+	if err != nil || status != "200 OK" {
+		t.Fatalf("status %q, %v", status, err)
+	}
+	if gotBody != string(r.Body) || gotType != "application/json" {
+		t.Errorf("server got %q (%s), want %q", gotBody, gotType, r.Body)
+	}
+
+	r, _ = newAPIRequest(server.URL+"/accounts/nobody", "nobody", 14, goldenVectors[14])
+	if _, err := sendAPIRequest(context.Background(), server.Client(), r); err == nil || !strings.Contains(err.Error(), "no such account") {
+		t.Errorf("404: got %v, want the response body in the error", err)
+	}
+}
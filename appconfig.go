@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// appConfigName is the optional shared defaults file, looked for next to the
+// executable first (for a portable copy) and then in the user config
+// directory, e.g. ~/.config/eqemu-password-hasher/config.json.
+const appConfigName = "config.json"
+
+// appConfig is the schema of config.json. Every field is optional; a missing
+// one leaves the built-in default or saved preference alone. Unknown fields
+// are an error, so a typo isn't silently ignored. Example:
+//
+//	{
+//	  "mode": 13,                          // default mode, see -list-modes
+//	  "argon2": {"m": 65536, "t": 2, "p": 1}, // KiB, passes, lanes
+//	  "scrypt": {"ln": 14, "r": 8, "p": 1},   // N = 2^ln
+//	  "argon2_salt_length": 16,
+//	  "max_password_length": 1024,         // 0 for no limit
+//	  "normalize_unicode": false,
+//	  "show_fork_modes": false,
+//	  "show_experimental_modes": false,    // SHA3 modes 19-26
+//	  "verify_only": false,                // as -verify-only
+//	  "language": "en",
+//	  "theme": "Dark"                      // System, Light or Dark
+//	}
+//
+// (JSON has no comments; they are only for this description.) The values
+// apply at startup. CLI flags and changes made in the window override them
+// for that session, but the file wins again at the next start.
+type appConfig struct {
+	Mode                  *int             `json:"mode"`
+	Argon2                *appConfigArgon2 `json:"argon2"`
+	SCrypt                *appConfigSCrypt `json:"scrypt"`
+	Argon2SaltLength      *int             `json:"argon2_salt_length"`
+	MaxPasswordLength     *int             `json:"max_password_length"`
+	NormalizeUnicode      *bool            `json:"normalize_unicode"`
+	ShowForkModes         *bool            `json:"show_fork_modes"`
+	ShowExperimentalModes *bool            `json:"show_experimental_modes"`
+	VerifyOnly            *bool            `json:"verify_only"`
+	Language              *string          `json:"language"`
+	Theme                 *string          `json:"theme"`
+}
+
+type appConfigArgon2 struct {
+	Memory  uint32 `json:"m"`
+	Time    uint32 `json:"t"`
+	Threads uint8  `json:"p"`
+}
+
+type appConfigSCrypt struct {
+	LogN uint32 `json:"ln"`
+	R    uint32 `json:"r"`
+	P    uint32 `json:"p"`
+}
+
+// appConfigPaths returns where config.json is looked for, in order.
+func appConfigPaths() []string {
+	var paths []string
+	if exe, err := os.Executable(); err == nil {
+		paths = append(paths, filepath.Join(filepath.Dir(exe), appConfigName))
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "eqemu-password-hasher", appConfigName))
+	}
+	return paths
+}
+
+// loadAppConfig reads and validates the first config.json found in paths. It
+// returns a zero appConfig and path "" if there is none.
+func loadAppConfig(paths []string) (cfg appConfig, path string, err error) {
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return appConfig{}, path, err
+		}
+		cfg, err := parseAppConfig(data)
+		if err != nil {
+			return appConfig{}, path, fmt.Errorf("%s: %w", path, err)
+		}
+		return cfg, path, nil
+	}
+	return appConfig{}, "", nil
+}
+
+// parseAppConfig decodes config.json and checks every value it sets.
+func parseAppConfig(data []byte) (appConfig, error) {
+	var cfg appConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return appConfig{}, err
+	}
+
+	if cfg.Mode != nil {
+		if _, ok := lookupMode(*cfg.Mode); !ok {
+			return appConfig{}, fmt.Errorf("unsupported mode %d", *cfg.Mode)
+		}
+	}
+	if cfg.Argon2 != nil {
+		params := cfg.Argon2.params()
+		if params.Threads == 0 {
+			return appConfig{}, fmt.Errorf("argon2: p must be at least 1")
+		}
+		if err := params.checkMinimum(); err != nil {
+			return appConfig{}, fmt.Errorf("argon2: %w", err)
+		}
+		if params.Memory > argon2MaxMemoryKiB {
+			return appConfig{}, fmt.Errorf("argon2: memory cost m=%d KiB exceeds the %d KiB limit", params.Memory, argon2MaxMemoryKiB)
+		}
+	}
+	if cfg.SCrypt != nil {
+		params := cfg.SCrypt.params()
+		if err := params.checkMinimum(); err != nil {
+			return appConfig{}, fmt.Errorf("scrypt: %w", err)
+		}
+		if err := params.checkMemory(); err != nil {
+			return appConfig{}, fmt.Errorf("scrypt: %w", err)
+		}
+	}
+	if cfg.Argon2SaltLength != nil {
+		if err := checkArgon2SaltLen(*cfg.Argon2SaltLength); err != nil {
+			return appConfig{}, err
+		}
+	}
+	if cfg.MaxPasswordLength != nil && *cfg.MaxPasswordLength < 0 {
+		return appConfig{}, fmt.Errorf("max_password_length must not be negative")
+	}
+	if cfg.Language != nil && !slices.ContainsFunc(languages, func(l language) bool { return l.Code == *cfg.Language }) {
+		return appConfig{}, fmt.Errorf("unknown language %q", *cfg.Language)
+	}
+	if cfg.Theme != nil && *cfg.Theme != "System" && *cfg.Theme != "Light" && *cfg.Theme != "Dark" {
+		return appConfig{}, fmt.Errorf("theme must be System, Light or Dark, not %q", *cfg.Theme)
+	}
+	return cfg, nil
+}
+
+func (c appConfigArgon2) params() argon2Params {
+	return argon2Params{Memory: c.Memory, Time: c.Time, Threads: c.Threads}
+}
+
+func (c appConfigSCrypt) params() scryptParams {
+	return scryptParams{LogN: c.LogN, R: c.R, P: c.P}
+}
+
+// applyKDFParams sets the parameters hashArgon2 and hashSCrypt use, falling
+// back to the built-in INTERACTIVE ones. The other settings are applied by
+// the caller, since the CLI keeps them in flags and the window in
+// preferences.
+func (c appConfig) applyKDFParams() {
+	argon2Cost, scryptCost := argon2Interactive, scryptInteractive
+	if c.Argon2 != nil {
+		argon2Cost = c.Argon2.params()
+	}
+	if c.SCrypt != nil {
+		scryptCost = c.SCrypt.params()
+	}
+	setGenerateParams(argon2Cost, scryptCost)
+}
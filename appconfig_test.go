@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseAppConfig(t *testing.T) {
+	cfg, err := parseAppConfig([]byte(`{
+		"mode": 13,
+		"argon2": {"m": 65536, "t": 2, "p": 1},
+		"scrypt": {"ln": 15, "r": 8, "p": 1},
+		"argon2_salt_length": 32,
+		"max_password_length": 0,
+		"normalize_unicode": true,
+		"language": "pt",
+		"theme": "Dark"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Mode == nil || *cfg.Mode != 13 {
+		t.Errorf("mode = %v, want 13", cfg.Mode)
+	}
+	if got := cfg.Argon2.params(); got != (argon2Params{Memory: 65536, Time: 2, Threads: 1}) {
+		t.Errorf("argon2 = %+v", got)
+	}
+	if got := cfg.SCrypt.params(); got != (scryptParams{LogN: 15, R: 8, P: 1}) {
+		t.Errorf("scrypt = %+v", got)
+	}
+	if cfg.ShowForkModes != nil {
+		t.Errorf("show_fork_modes = %v, want unset", *cfg.ShowForkModes)
+	}
+
+	if cfg, err := parseAppConfig([]byte(`{}`)); err != nil || cfg.Mode != nil || cfg.Argon2 != nil {
+		t.Errorf("empty config: %+v, %v", cfg, err)
+	}
+
+	for _, bad := range []string{
+		`{"mode": 99}`,
+		`{"mdoe": 13}`,
+		`{"argon2": {"m": 4, "t": 1, "p": 1}}`,
+		`{"argon2": {"m": 65536, "t": 2, "p": 0}}`,
+		`{"scrypt": {"ln": 4, "r": 8, "p": 1}}`,
+		`{"argon2_salt_length": 4}`,
+		`{"max_password_length": -1}`,
+		`{"language": "xx"}`,
+		`{"theme": "Blue"}`,
+		`{"mode": "13"}`,
+	} {
+		if _, err := parseAppConfig([]byte(bad)); err == nil {
+			t.Errorf("%s: no error", bad)
+		}
+	}
+}
+
+func TestLoadAppConfig(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing", appConfigName)
+	first := filepath.Join(dir, "first.json")
+	second := filepath.Join(dir, "second.json")
+	os.WriteFile(first, []byte(`{"mode": 5}`), 0o600)
+	os.WriteFile(second, []byte(`{"mode": 9}`), 0o600)
+
+	cfg, path, err := loadAppConfig([]string{missing, first, second})
+	if err != nil || path != first || cfg.Mode == nil || *cfg.Mode != 5 {
+		t.Errorf("got %+v from %q, %v; want mode 5 from %q", cfg, path, err, first)
+	}
+
+	cfg, path, err = loadAppConfig([]string{missing})
+	if err != nil || path != "" || cfg.Mode != nil {
+		t.Errorf("no file: got %+v from %q, %v", cfg, path, err)
+	}
+
+	// A broken file is reported, not skipped in favour of the next one.
+	os.WriteFile(first, []byte(`{"mode": 5`), 0o600)
+	if _, _, err := loadAppConfig([]string{first, second}); err == nil || !strings.Contains(err.Error(), first) {
+		t.Errorf("broken file: error %v, want one naming %s", err, first)
+	}
+}
+
+func TestApplyKDFParams(t *testing.T) {
+	defer func() { argon2Generate, scryptGenerate = argon2Interactive, scryptInteractive }()
+
+	cfg, err := parseAppConfig([]byte(`{"argon2": {"m": 16384, "t": 3, "p": 1}, "scrypt": {"ln": 15, "r": 8, "p": 2}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.applyKDFParams()
+
+	hash, err := hashArgon2(goldenPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params, _, _, err := parseArgon2PHC(hash); err != nil || params != cfg.Argon2.params() {
+		t.Errorf("argon2 hash %q has %+v, %v; want %+v", hash, params, err, cfg.Argon2.params())
+	}
+	hash, err = hashSCrypt(goldenPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params, _, _, err := parseSCryptMCF(hash); err != nil || params != cfg.SCrypt.params() {
+		t.Errorf("scrypt hash %q has %+v, %v; want %+v", hash, params, err, cfg.SCrypt.params())
+	}
+
+	appConfig{}.applyKDFParams()
+	if argon2Generate != argon2Interactive || scryptGenerate != scryptInteractive {
+		t.Errorf("empty config left %+v, %+v", argon2Generate, scryptGenerate)
+	}
+}
+
+func TestRunCLIConfig(t *testing.T) {
+	defer func(length, salt int) { maxPasswordLength, argon2SaltLen = length, salt }(maxPasswordLength, argon2SaltLen)
+	defer func() { argon2Generate, scryptGenerate = argon2Interactive, scryptInteractive }()
+
+	path := filepath.Join(t.TempDir(), appConfigName)
+	os.WriteFile(path, []byte(fmt.Sprintf(`{"mode": 1, "max_password_length": %d}`, len(goldenPassword))), 0o600)
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-cli", "-config", path, "-password", goldenPassword}, strings.NewReader(""), &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if got := strings.TrimSpace(stdout.String()); got != goldenVectors[1] {
+		t.Errorf("config mode 1: got %q, want %q", got, goldenVectors[1])
+	}
+	if code := runCLI([]string{"-cli", "-config", path, "-password", goldenPassword + "x"}, strings.NewReader(""), &stdout, &stderr); code == exitOK {
+		t.Error("password over the configured max_password_length was accepted")
+	}
+
+	// Flags win over the file.
+	stdout.Reset()
+	code = runCLI([]string{"-cli", "-config", path, "-mode", "2", "-username", goldenUsername, "-password", goldenPassword}, strings.NewReader(""), &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if got := strings.TrimSpace(stdout.String()); got != goldenVectors[2] {
+		t.Errorf("-mode 2: got %q, want %q", got, goldenVectors[2])
+	}
+	stdout.Reset()
+	code = runCLI([]string{"-cli", "-config", path, "-mode", "1", "-max-password-length", "0", "-password", goldenPassword + "x"}, strings.NewReader(""), &stdout, &stderr)
+	if code != exitOK {
+		t.Errorf("-max-password-length 0: exit code %d, stderr: %s", code, stderr.String())
+	}
+
+	// A configured mode that needs a username doesn't stop -verify from
+	// detecting the mode of a hash that doesn't.
+	os.WriteFile(path, []byte(`{"mode": 6}`), 0o600)
+	stdout.Reset()
+	code = runCLI([]string{"-cli", "-config", path, "-verify", "-hash", goldenVectors[14], "-password", goldenPassword}, strings.NewReader(""), &stdout, &stderr)
+	if code != exitOK || !strings.Contains(stdout.String(), "PASS") {
+		t.Errorf("-verify with config mode 6: exit code %d, stdout %q, stderr %q", code, stdout.String(), stderr.String())
+	}
+
+	os.WriteFile(path, []byte(`{"mode": 99}`), 0o600)
+	stderr.Reset()
+	if code := runCLI([]string{"-cli", "-config", path, "-password", goldenPassword}, strings.NewReader(""), &stdout, &stderr); code != exitUsage || !strings.Contains(stderr.String(), "config") {
+		t.Errorf("invalid config: exit code %d, stderr %q", code, stderr.String())
+	}
+	if code := runCLI([]string{"-cli", "-config", filepath.Join(t.TempDir(), "none.json"), "-password", goldenPassword}, strings.NewReader(""), &stdout, &stderr); code != exitUsage {
+		t.Errorf("missing -config file: exit code %d, want %d", code, exitUsage)
+	}
+}
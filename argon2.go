@@ -0,0 +1,193 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// errUnsupportedArgon2Variant is returned for argon2i and argon2d hashes.
+// libsodium's crypto_pwhash_str, and so EQEmu loginserver, only produces
+// argon2id, and verifying another variant as if it were argon2id would just
+// give a misleading FAIL.
+var errUnsupportedArgon2Variant = errors.New("only argon2id is supported by EQEmu loginserver")
+
+// argon2Variant returns the variant name of an Argon2 PHC string, e.g.
+// "argon2id", or "" if s doesn't look like one.
+func argon2Variant(s string) string {
+	fields := strings.SplitN(s, "$", 3)
+	if len(fields) < 2 || fields[0] != "" || !strings.HasPrefix(fields[1], "argon2") {
+		return ""
+	}
+	return fields[1]
+}
+
+// argon2Params are the cost parameters stored in an Argon2 PHC string.
+type argon2Params struct {
+	Memory  uint32 // KiB
+	Time    uint32
+	Threads uint8
+}
+
+func (p argon2Params) String() string {
+	return fmt.Sprintf("m=%d, t=%d, p=%d", p.Memory, p.Time, p.Threads)
+}
+
+// parseArgon2PHC strictly validates an Argon2id PHC string as produced by
+// libsodium's crypto_pwhash_str:
+//
+//	$argon2id$v=19$m=65536,t=2,p=1$<salt>$<hash>
+//
+// and returns its parameters and the decoded salt and hash. The errors are
+// meant to be shown to the user to help spot corrupted database entries.
+func parseArgon2PHC(s string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	switch variant := argon2Variant(s); variant {
+	case "argon2id":
+	case "argon2i", "argon2d":
+		return params, nil, nil, fmt.Errorf("wrong variant: %s not supported, %w", variant, errUnsupportedArgon2Variant)
+	case "":
+		return params, nil, nil, fmt.Errorf("not an Argon2 hash: must start with $argon2id$")
+	default:
+		return params, nil, nil, fmt.Errorf("unknown Argon2 variant %q", variant)
+	}
+
+	fields := strings.Split(s, "$")
+	if len(fields) != 6 {
+		if len(fields) == 5 && !strings.HasPrefix(fields[2], "v=") {
+			return params, nil, nil, fmt.Errorf("missing version field (expected v=%d)", argon2.Version)
+		}
+		return params, nil, nil, fmt.Errorf("expected 5 $-separated fields, found %d", len(fields)-1)
+	}
+
+	version, ok := strings.CutPrefix(fields[2], "v=")
+	if !ok {
+		return params, nil, nil, fmt.Errorf("missing version field (expected v=%d)", argon2.Version)
+	}
+	if version != strconv.Itoa(argon2.Version) {
+		return params, nil, nil, fmt.Errorf("unsupported version v=%s (expected v=%d)", version, argon2.Version)
+	}
+
+	var err error
+	if params, err = parseArgon2Params(fields[3]); err != nil {
+		return params, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(fields[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid salt encoding: %v", err)
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(fields[5])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid hash encoding: %v", err)
+	}
+	if len(hash) == 0 {
+		return params, nil, nil, fmt.Errorf("missing hash")
+	}
+	if len(hash) < argon2MinKeyLen {
+		return params, nil, nil, fmt.Errorf("hash is %d bytes, libsodium requires at least %d", len(hash), argon2MinKeyLen)
+	}
+	return params, salt, hash, nil
+}
+
+// parseArgon2Params parses the "m=65536,t=2,p=1" parameter field. The three
+// parameters must all be present and in that order.
+func parseArgon2Params(field string) (argon2Params, error) {
+	var params argon2Params
+
+	parts := strings.Split(field, ",")
+	if len(parts) != 3 {
+		return params, fmt.Errorf("malformed parameters %q (expected m=..,t=..,p=..)", field)
+	}
+	values := make([]uint64, 3)
+	for i, name := range []string{"m", "t", "p"} {
+		value, ok := strings.CutPrefix(parts[i], name+"=")
+		if !ok {
+			return params, fmt.Errorf("malformed parameters %q (expected m=..,t=..,p=..)", field)
+		}
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || n == 0 {
+			return params, fmt.Errorf("invalid %s parameter %q", name, value)
+		}
+		values[i] = n
+	}
+	if values[2] > 255 {
+		return params, fmt.Errorf("invalid p parameter %d (at most 255 supported)", values[2])
+	}
+
+	params = argon2Params{
+		Memory:  uint32(values[0]),
+		Time:    uint32(values[1]),
+		Threads: uint8(values[2]),
+	}
+	if params.Memory < 8*uint32(params.Threads) {
+		return params, fmt.Errorf("invalid m parameter %d (must be at least 8*p)", params.Memory)
+	}
+	return params, nil
+}
+
+// crypto_pwhash_argon2id_OPSLIMIT_MIN and MEMLIMIT_MIN (8192 bytes). Hashes
+// are never generated below these, though existing ones still verify.
+const (
+	argon2MinTime      = 1
+	argon2MinMemoryKiB = 8
+)
+
+// checkMinimum refuses parameters below libsodium's minimums.
+func (p argon2Params) checkMinimum() error {
+	if p.Time < argon2MinTime {
+		return fmt.Errorf("Argon2 time cost t=%d is below the minimum of t=%d", p.Time, argon2MinTime)
+	}
+	if p.Memory < argon2MinMemoryKiB {
+		return fmt.Errorf("Argon2 memory cost m=%d KiB is below the minimum of m=%d KiB", p.Memory, argon2MinMemoryKiB)
+	}
+	return nil
+}
+
+// belowInteractive reports whether p is cheaper in memory or time than the
+// loginserver's INTERACTIVE defaults.
+func (p argon2Params) belowInteractive() bool {
+	return p.Memory < argon2Interactive.Memory || p.Time < argon2Interactive.Time
+}
+
+// ARGON2_MIN_OUTLEN: libsodium refuses shorter stored hashes.
+const argon2MinKeyLen = 16
+
+// compareDerived compares a stored hash with one derived from the password in
+// constant time. Both verifiers derive as many bytes as are stored, as
+// libsodium does, so differing lengths mean a bug or an incompatible
+// parameter set rather than a wrong password, and are an error instead of a
+// silent false.
+func compareDerived(stored, computed []byte) (bool, error) {
+	if len(stored) != len(computed) {
+		return false, fmt.Errorf("hash length mismatch: stored %d, computed %d", len(stored), len(computed))
+	}
+	return subtle.ConstantTimeCompare(computed, stored) == 1, nil
+}
+
+// Largest Argon2 memory cost verifyArgon2 will attempt, in KiB (4 GiB). A
+// corrupted or hostile m= value could otherwise ask for terabytes.
+const argon2MaxMemoryKiB = 4 << 20
+
+// verifyArgon2 checks password against an Argon2id PHC string. Like
+// libsodium's crypto_pwhash_str_verify, the cost parameters and salt all come
+// from the stored string, never from argon2Generate, so hashes made with
+// MODERATE or custom limits verify whatever new hashes use.
+func verifyArgon2(storedHash, password string) (bool, error) {
+	params, salt, hash, err := parseArgon2PHC(storedHash)
+	if err != nil {
+		return false, err
+	}
+	if params.Memory > argon2MaxMemoryKiB {
+		return false, fmt.Errorf("memory cost m=%d KiB exceeds the %d KiB limit", params.Memory, argon2MaxMemoryKiB)
+	}
+
+	computed := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(hash)))
+	return compareDerived(hash, computed)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseArgon2PHC(t *testing.T) {
+	params, salt, hash, err := parseArgon2PHC(goldenVectors[13])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (argon2Params{Memory: 65536, Time: 2, Threads: 1}); params != want {
+		t.Errorf("params = %v, want %v", params, want)
+	}
+	if len(salt) != 16 || salt[15] != 15 {
+		t.Errorf("salt = %x, want 000102...0f", salt)
+	}
+	if len(hash) != 32 {
+		t.Errorf("hash is %d bytes, want 32", len(hash))
+	}
+}
+
+func TestParseArgon2PHCErrors(t *testing.T) {
+	const salt, hash = "AAECAwQFBgcICQoLDA0ODw", "JSYLtExpEDLASlDIanNns9CIqZQxk26uZJnOpHInGbY"
+	tests := []struct {
+		hash string
+		err  string
+	}{
+		{"$7$C6..../....", "not an Argon2 hash"},
+		{"$argon2i$v=19$m=65536,t=2,p=1$" + salt + "$" + hash, "wrong variant: argon2i"},
+		{"$argon2d$v=19$m=65536,t=2,p=1$" + salt + "$" + hash, "wrong variant: argon2d"},
+		{"$argon2x$v=19$m=65536,t=2,p=1$" + salt + "$" + hash, "unknown Argon2 variant"},
+		{"$argon2id$m=65536,t=2,p=1$" + salt + "$" + hash, "missing version field"},
+		{"$argon2id$v=16$m=65536,t=2,p=1$" + salt + "$" + hash, "unsupported version"},
+		{"$argon2id$v=19$t=2,m=65536,p=1$" + salt + "$" + hash, "malformed parameters"},
+		{"$argon2id$v=19$m=65536,t=0,p=1$" + salt + "$" + hash, "invalid t parameter"},
+		{"$argon2id$v=19$m=4,t=2,p=1$" + salt + "$" + hash, "invalid m parameter"},
+		{"$argon2id$v=19$m=65536,t=2,p=1$" + salt + "!$" + hash, "invalid salt encoding"},
+		{"$argon2id$v=19$m=65536,t=2,p=1$" + salt + "$", "missing hash"},
+		{"$argon2id$v=19$m=65536,t=2,p=1$" + salt + "$" + hash[:20], "hash is 15 bytes, libsodium requires at least 16"},
+		{"$argon2id$v=19$m=65536,t=2,p=1$" + salt, "expected 5"},
+	}
+	for _, tt := range tests {
+		_, _, _, err := parseArgon2PHC(tt.hash)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got error %v, want %q", tt.hash, err, tt.err)
+		}
+	}
+}
+
+func TestArgon2Minimum(t *testing.T) {
+	for _, weak := range []argon2Params{{Memory: 4, Time: 1, Threads: 1}, {Memory: 65536, Time: 0, Threads: 1}} {
+		if _, err := hashArgon2WithParams(goldenPassword, make([]byte, 16), weak, argon2KeyLen); err == nil || !strings.Contains(err.Error(), "minimum") {
+			t.Errorf("%v: got %v, want a minimum error", weak, err)
+		}
+	}
+	// libsodium's minimum is accepted, but warned about.
+	lowest := argon2Params{Memory: argon2MinMemoryKiB, Time: argon2MinTime, Threads: 1}
+	if err := lowest.checkMinimum(); err != nil {
+		t.Errorf("minimum refused: %v", err)
+	}
+	if note := kdfCostNote(13, lowest, scryptParams{}); !strings.Contains(note, argon2Interactive.String()) {
+		t.Errorf("m=8, t=1: note %q", note)
+	}
+	if note := kdfCostNote(13, argon2Interactive, scryptParams{}); note != "" {
+		t.Errorf("interactive: unexpected note %q", note)
+	}
+}
+
+func TestArgon2HashLength(t *testing.T) {
+	// libsodium derives as many bytes as are stored, so a 64-byte hash from
+	// another tool verifies.
+	long, err := hashArgon2WithParams(goldenPassword, make([]byte, 16), argon2Params{Memory: 64, Time: 1, Threads: 1}, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := verifyArgon2(long, goldenPassword); !ok || err != nil {
+		t.Errorf("64-byte hash: %v, %v", ok, err)
+	}
+	if ok, err := verifyArgon2(long, "wrong"); ok || err != nil {
+		t.Errorf("64-byte hash, wrong password: %v, %v", ok, err)
+	}
+
+	_, err = compareDerived(make([]byte, 64), make([]byte, 32))
+	if err == nil || err.Error() != "hash length mismatch: stored 64, computed 32" {
+		t.Errorf("mismatched lengths: %v", err)
+	}
+}
+
+// argon2Moderate is goldenPassword hashed by libsodium's
+// crypto_pwhash_argon2id_str with the MODERATE limits (3 passes, 256 MiB).
+const argon2Moderate = "$argon2id$v=19$m=262144,t=3,p=1$YD7Wp2FsoFnqDHMVvEHiKg$ckeQtBRO0L0Dg3LQsHeta4sNWpLUVH168vrzBTLPDrM"
+
+func TestVerifyArgon2Moderate(t *testing.T) {
+	// Generation parameters far from the stored ones must not leak into
+	// verification.
+	defer func(old argon2Params) { argon2Generate = old }(argon2Generate)
+	argon2Generate = argon2Params{Memory: argon2MinMemoryKiB, Time: argon2MinTime, Threads: 1}
+
+	mode, err := verifySelected(argon2Moderate, "", goldenPassword, 13)
+	if mode != 13 || err != nil {
+		t.Errorf("MODERATE hash: mode %d, %v", mode, err)
+	}
+	if ok, err := verifyArgon2(argon2Moderate, "kaladim#4ever"); ok || err != nil {
+		t.Errorf("MODERATE hash, wrong password: %v, %v", ok, err)
+	}
+}
+
+func TestArgon2VariantDetection(t *testing.T) {
+	for _, variant := range []string{"argon2i", "argon2d"} {
+		hash := strings.Replace(goldenVectors[13], "argon2id", variant, 1)
+		if got := argon2Variant(hash); got != variant {
+			t.Errorf("argon2Variant(%q) = %q", hash, got)
+		}
+		if _, _, _, err := parseArgon2PHC(hash); !errors.Is(err, errUnsupportedArgon2Variant) {
+			t.Errorf("%s: got %v, want errUnsupportedArgon2Variant", variant, err)
+		}
+	}
+	if got := argon2Variant(goldenVectors[14]); got != "" {
+		t.Errorf("argon2Variant(scrypt hash) = %q, want \"\"", got)
+	}
+}
+
+// FuzzVerifyArgon2 feeds verifyArgon2 arbitrary stored hashes, such as
+// corrupted database values, which must be rejected without a panic.
+func FuzzVerifyArgon2(f *testing.F) {
+	golden := goldenVectors[13]
+	for _, seed := range []string{
+		golden, "", "$", "$argon2id$", "$argon2id$v=19$", "$argon2id$v=19$m=65536,t=2,p=1$",
+		"$argon2id$v=19$m=65536,t=2,p=1$$", golden + "$", golden[:len(golden)-1],
+		strings.Replace(golden, ",", "", -1), "$argon2i$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$aGFzaA", goldenVectors[14],
+	} {
+		f.Add(seed)
+	}
+	// Deriving is skipped for anything costlier than the golden vector, or
+	// the fuzzer spends its time (and memory) inside Argon2.
+	f.Fuzz(func(t *testing.T, hash string) {
+		if params, _, _, err := parseArgon2PHC(hash); err == nil && (params.Memory > argon2Interactive.Memory || params.Time > argon2Interactive.Time) {
+			t.Skip()
+		}
+		ok, err := verifyArgon2(hash, goldenPassword)
+		if ok && err != nil {
+			t.Errorf("%q: matched with error %v", hash, err)
+		}
+		if ok && hash != golden {
+			t.Errorf("%q matched the golden password", hash)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// auditLog records generated hashes, database writes and API posts when
+// enabled in Settings. Entries name the mode and account and a short prefix
+// of the hash; the password and full hash are never logged.
+var auditLog = log.New(io.Discard, "", log.LstdFlags)
+
+var (
+	auditMu   sync.Mutex
+	auditFile *os.File
+)
+
+// Characters of a hash kept in audit entries: enough to match an entry to a
+// database row, not enough to be useful to an attacker.
+const auditHashPrefixLen = 12
+
+// defaultAuditLogPath is audit.log in the user's config directory.
+func defaultAuditLogPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "eqemu-password-hasher-audit.log"
+	}
+	return filepath.Join(dir, "eqemu-password-hasher", "audit.log")
+}
+
+// setAuditLogFile starts appending audit entries to path, or stops logging
+// if path is "".
+func setAuditLogFile(path string) error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	var f *os.File
+	if path != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return err
+		}
+		var err error
+		if f, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600); err != nil {
+			return err
+		}
+		auditLog.SetOutput(f)
+	} else {
+		auditLog.SetOutput(io.Discard)
+	}
+	if auditFile != nil {
+		auditFile.Close()
+	}
+	auditFile = f
+	return nil
+}
+
+// auditHash shortens a hash for the audit log.
+func auditHash(hash string) string {
+	if len(hash) <= auditHashPrefixLen {
+		return hash
+	}
+	return hash[:auditHashPrefixLen] + "..."
+}
+
+// auditResult formats the outcome of an operation for the audit log.
+func auditResult(err error) string {
+	if err != nil {
+		return fmt.Sprintf("failed (%v)", err)
+	}
+	return "ok"
+}
+
+func auditGenerate(mode int, username, hash string) {
+	auditLog.Printf("generate mode=%d username=%q hash=%s", mode, username, auditHash(hash))
+}
+
+func auditDBWrite(action string, target accountTarget, account, hash string, err error) {
+	auditLog.Printf("db-%s table=%s account=%q hash=%s result=%s",
+		action, target.table(), account, auditHash(hash), auditResult(err))
+}
+
+// auditAPIPost records a hash sent to a hosting panel. Only the host is
+// logged, since the URL itself may carry the hash or an API key.
+func auditAPIPost(r apiRequest, username, hash string, err error) {
+	host := r.URL
+	if u, parseErr := url.Parse(r.URL); parseErr == nil {
+		host = u.Host
+	}
+	auditLog.Printf("api-post host=%s username=%q hash=%s result=%s",
+		host, username, auditHash(hash), auditResult(err))
+}
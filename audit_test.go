@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuditNeverLogsSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	auditLog.SetOutput(&buf)
+	defer auditLog.SetOutput(io.Discard)
+
+	hash := goldenVectors[14]
+	auditGenerate(14, goldenUsername, hash)
+	auditDBWrite("update", targetAdmin, goldenUsername, hash, errors.New("connection refused"))
+	auditAPIPost(apiRequest{URL: "https://panel.example.com/set?key=secret&h=" + hash}, goldenUsername, hash, nil)
+
+	got := buf.String()
+	if strings.Contains(got, hash) || strings.Contains(got, goldenPassword) || strings.Contains(got, "secret") {
+		t.Errorf("audit log contains the full hash or password:\n%s", got)
+	}
+	for _, want := range []string{
+		"generate mode=14 username=\"Gearheart\" hash=" + hash[:auditHashPrefixLen] + "...",
+		"db-update table=login_server_admins account=\"Gearheart\"",
+		"result=failed (connection refused)",
+		"api-post host=panel.example.com username=\"Gearheart\"",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("audit log missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestSetAuditLogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "audit.log")
+	if err := setAuditLogFile(path); err != nil {
+		t.Fatal(err)
+	}
+	auditGenerate(1, "", goldenVectors[1])
+	if err := setAuditLogFile(""); err != nil {
+		t.Fatal(err)
+	}
+	auditGenerate(2, "", goldenVectors[2]) // disabled, not written
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 1 {
+		t.Errorf("got %d audit lines, want 1:\n%s", lines, data)
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// batchVerifySummary counts the outcomes of a batchVerify run.
+type batchVerifySummary struct {
+	Total   int
+	Passed  int
+	Failed  int
+	Errors  int
+	Elapsed time.Duration
+}
+
+// batchVerify reads username,hash,password rows from r, checks each with
+// verifyAnyMode and writes a report CSV to w. A header row is skipped if the
+// first row starts with "username". The report never contains passwords.
+// progress, if non-nil, is called after each row.
+func batchVerify(r io.Reader, w io.Writer, progress func(done, total int)) (batchVerifySummary, error) {
+	var summary batchVerifySummary
+	start := time.Now()
+
+	in := csv.NewReader(r)
+	in.FieldsPerRecord = -1 // report bad rows instead of aborting
+	rows, err := in.ReadAll()
+	if err != nil {
+		return summary, fmt.Errorf("reading CSV: %w", err)
+	}
+
+	first := 0
+	if len(rows) > 0 && len(rows[0]) > 0 && strings.EqualFold(strings.TrimSpace(rows[0][0]), "username") {
+		first = 1
+	}
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"line", "username", "result", "mode", "reason"})
+
+	total := len(rows) - first
+	for i, row := range rows[first:] {
+		line := strconv.Itoa(first + i + 1)
+		result, mode, reason := verifyRow(row)
+
+		username := ""
+		if len(row) > 0 {
+			username = strings.TrimSpace(row[0])
+		}
+		modeText := ""
+		if mode > 0 {
+			modeText = strconv.Itoa(mode)
+		}
+		out.Write([]string{line, username, result, modeText, reason})
+
+		summary.Total++
+		switch result {
+		case "PASS":
+			summary.Passed++
+		case "FAIL":
+			summary.Failed++
+		default:
+			summary.Errors++
+		}
+		if progress != nil {
+			progress(i+1, total)
+		}
+	}
+
+	out.Flush()
+	summary.Elapsed = roundElapsed(time.Since(start))
+	return summary, out.Error()
+}
+
+// verifyRow checks one username,hash,password row and returns PASS, FAIL or
+// ERROR with the matching mode and a human-readable reason. Spaces around
+// the username and hash are ignored, but the password is used as given
+// since spaces can be part of it.
+func verifyRow(row []string) (result string, mode int, reason string) {
+	if len(row) != 3 {
+		return "ERROR", 0, fmt.Sprintf("expected 3 fields (username,hash,password), found %d", len(row))
+	}
+	username, hash, password := strings.TrimSpace(row[0]), strings.TrimSpace(row[1]), row[2]
+
+	mode, err := verifyAnyMode(hash, username, password)
+	if err != nil {
+		return "ERROR", 0, err.Error()
+	}
+
+	candidates := detectHashModes(hash)
+	skipped := skippedModes(hash, username)
+	var tried []int
+	for _, m := range candidates {
+		if !slices.Contains(skipped, m) {
+			tried = append(tried, m)
+		}
+	}
+	noun := "modes"
+	if len(tried) == 1 {
+		noun = "mode"
+	}
+	switch {
+	case mode > 0:
+		return "PASS", mode, fmt.Sprintf("matched mode %d (%s)", mode, modeName(mode))
+	case len(candidates) == 1:
+		return "FAIL", 0, fmt.Sprintf("password does not match this %s hash", modeName(candidates[0]))
+	case len(skipped) > 0:
+		return "FAIL", 0, fmt.Sprintf("no match for %s %s; modes %s skipped because the username is empty", noun, modeList(tried), modeList(skipped))
+	default:
+		return "FAIL", 0, fmt.Sprintf("no match for %s %s", noun, modeList(tried))
+	}
+}
+
+// batchHashSummary counts the outcomes of a batchHash run.
+type batchHashSummary struct {
+	Rows    int
+	Hashes  int
+	Errors  int
+	Elapsed time.Duration
+}
+
+// String summarizes a batchHash run for the -batch report on stderr.
+func (s batchHashSummary) String() string {
+	return fmt.Sprintf("processed %d hashes for %d rows in %v: %d succeeded, %d failed",
+		s.Hashes+s.Errors, s.Rows, s.Elapsed, s.Hashes, s.Errors)
+}
+
+// roundElapsed rounds a batch run's duration for display.
+func roundElapsed(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(100 * time.Millisecond)
+}
+
+// Worker pool sizes for batchHash. Argon2 and SCrypt need tens of MiB and
+// tens of milliseconds per hash, so they get a small pool of their own and
+// the cheap hex modes don't queue behind them.
+var (
+	batchFastWorkers = runtime.NumCPU()
+	batchSlowWorkers = min(runtime.NumCPU(), 4)
+)
+
+// isKDFMode reports whether mode is one of the deliberately slow modes.
+func isKDFMode(mode int) bool {
+	return mode == 13 || mode == 14
+}
+
+// parseModeList parses a comma-separated list of modes such as "1,5,9,13".
+func parseModeList(s string) ([]int, error) {
+	var modes []int
+	for _, field := range strings.Split(s, ",") {
+		mode, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("invalid mode %q", field)
+		}
+		if _, ok := lookupMode(mode); !ok {
+			return nil, fmt.Errorf("unsupported encryption mode: %d", mode)
+		}
+		if slices.Contains(modes, mode) {
+			return nil, fmt.Errorf("mode %d listed twice", mode)
+		}
+		modes = append(modes, mode)
+	}
+	return modes, nil
+}
+
+// batchHash reads username,password rows from r and writes a CSV with one
+// line,username,mode,hash,error row per input row and mode to w, in input
+// order. A header row is skipped if the first row starts with "username".
+// The output never contains passwords. progress, if non-nil, is called after
+// each hash from the worker goroutines.
+func batchHash(r io.Reader, w io.Writer, modes []int, progress func(done, total int)) (batchHashSummary, error) {
+	var summary batchHashSummary
+	start := time.Now()
+
+	in := csv.NewReader(r)
+	in.FieldsPerRecord = -1 // report bad rows instead of aborting
+	rows, err := in.ReadAll()
+	if err != nil {
+		return summary, fmt.Errorf("reading CSV: %w", err)
+	}
+	first := 0
+	if len(rows) > 0 && len(rows[0]) > 0 && strings.EqualFold(strings.TrimSpace(rows[0][0]), "username") {
+		first = 1
+	}
+	rows = rows[first:]
+
+	type job struct {
+		row, mode int
+	}
+	results := make([][]string, len(rows)*len(modes))
+	fast := make(chan job)
+	slow := make(chan job)
+
+	var done atomic.Int64
+	total := len(results)
+	work := func(jobs <-chan job, wg *sync.WaitGroup) {
+		defer wg.Done()
+		for j := range jobs {
+			results[j.row*len(modes)+j.mode] = hashRow(rows[j.row], modes[j.mode])
+			if progress != nil {
+				progress(int(done.Add(1)), total)
+			}
+		}
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < batchFastWorkers; i++ {
+		wg.Add(1)
+		go work(fast, &wg)
+	}
+	for i := 0; i < batchSlowWorkers; i++ {
+		wg.Add(1)
+		go work(slow, &wg)
+	}
+
+	// Fill the two queues concurrently so neither pool waits on the other.
+	feed := func(jobs chan<- job, slow bool) {
+		defer close(jobs)
+		for row := range rows {
+			for m, mode := range modes {
+				if isKDFMode(mode) == slow {
+					jobs <- job{row, m}
+				}
+			}
+		}
+	}
+	go feed(fast, false)
+	go feed(slow, true)
+	wg.Wait()
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"line", "username", "mode", "hash", "error"})
+	for i, result := range results {
+		out.Write(append([]string{strconv.Itoa(first + i/len(modes) + 1)}, result...))
+		if result[3] == "" {
+			summary.Hashes++
+		} else {
+			summary.Errors++
+		}
+	}
+	summary.Rows = len(rows)
+
+	out.Flush()
+	summary.Elapsed = roundElapsed(time.Since(start))
+	return summary, out.Error()
+}
+
+// hashRow hashes one username,password row with mode and returns the
+// username,mode,hash,error fields of its output row.
+func hashRow(row []string, mode int) []string {
+	modeText := strconv.Itoa(mode)
+	if len(row) != 2 {
+		username := ""
+		if len(row) > 0 {
+			username = row[0]
+		}
+		return []string{username, modeText, "", fmt.Sprintf("expected 2 fields (username,password), found %d", len(row))}
+	}
+	username, password := row[0], row[1]
+	if modeNeedsUsername[mode] && username == "" {
+		return []string{username, modeText, "", "skipped: username required"}
+	}
+	hash, err := eqcryptHash(username, password, mode)
+	if err != nil {
+		return []string{username, modeText, "", err.Error()}
+	}
+	return []string{username, modeText, outputHash(hash), ""}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBatchVerify(t *testing.T) {
+	spaced, err := eqcryptHash(goldenUsername, " "+goldenPassword, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := "username,hash,password\n" +
+		goldenUsername + "," + goldenVectors[3] + "," + goldenPassword + "\n" +
+		goldenUsername + "," + goldenVectors[14] + "," + goldenPassword + "\n" +
+		goldenUsername + "," + goldenVectors[8] + ",wrongpassword\n" +
+		"," + goldenVectors[6] + "," + goldenPassword + "\n" +
+		goldenUsername + ",garbage," + goldenPassword + "\n" +
+		"short,row\n" +
+		"," + goldenVectors[19] + ",wrongpassword\n" +
+		goldenUsername + "," + goldenVectors[9] + ",wrongpassword\n" +
+		" " + goldenUsername + ", " + spaced + ", " + goldenPassword + "\n"
+
+	var report bytes.Buffer
+	var calls int
+	summary, err := batchVerify(strings.NewReader(input), &report, func(done, total int) {
+		calls++
+		if total != 9 {
+			t.Errorf("progress total = %d, want 9", total)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Elapsed <= 0 {
+		t.Errorf("summary.Elapsed = %v, want a positive duration", summary.Elapsed)
+	}
+	summary.Elapsed = 0
+	if want := (batchVerifySummary{Total: 9, Passed: 3, Failed: 4, Errors: 2}); summary != want {
+		t.Errorf("summary = %+v, want %+v", summary, want)
+	}
+	if calls != 9 {
+		t.Errorf("progress called %d times, want 9", calls)
+	}
+
+	rows, err := csv.NewReader(&report).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]string{{"PASS", "3"}, {"PASS", "14"}, {"FAIL", ""}, {"FAIL", ""}, {"ERROR", ""}, {"ERROR", ""}, {"FAIL", ""}, {"FAIL", ""}, {"PASS", "3"}}
+	for i, w := range want {
+		row := rows[i+1]
+		if row[2] != w[0] || row[3] != w[1] {
+			t.Errorf("line %s: got %s mode %q (%s), want %s mode %q", row[0], row[2], row[3], row[4], w[0], w[1])
+		}
+		if strings.Contains(strings.Join(row, ","), goldenPassword) {
+			t.Errorf("line %s: report contains the password", row[0])
+		}
+	}
+	if !strings.Contains(rows[4][4], "username is empty") {
+		t.Errorf("expected the empty-username row to explain the skipped modes, got %q", rows[4][4])
+	}
+	if want := "no match for modes 15, 19; modes 16, 17, 18, 20, 21, 22 skipped because the username is empty"; rows[7][4] != want {
+		t.Errorf("64-character hash without a username: reason %q, want %q", rows[7][4], want)
+	}
+	if want := "no match for modes 9, 10, 11, 12, 23, 24, 25, 26"; rows[8][4] != want {
+		t.Errorf("128-character hash: reason %q, want %q", rows[8][4], want)
+	}
+	if rows[9][1] != goldenUsername {
+		t.Errorf("padded username reported as %q, want %q", rows[9][1], goldenUsername)
+	}
+}
+
+func TestBatchHash(t *testing.T) {
+	input := "username,password\n" +
+		goldenUsername + "," + goldenPassword + "\n" +
+		"," + goldenPassword + "\n" +
+		"short\n"
+	modes := []int{2, 13, 5}
+
+	var out bytes.Buffer
+	var calls int
+	var mu sync.Mutex
+	summary, err := batchHash(strings.NewReader(input), &out, modes, func(done, total int) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Elapsed <= 0 {
+		t.Errorf("summary.Elapsed = %v, want a positive duration", summary.Elapsed)
+	}
+	summary.Elapsed = 0
+	if want := (batchHashSummary{Rows: 3, Hashes: 5, Errors: 4}); summary != want {
+		t.Errorf("summary = %+v, want %+v", summary, want)
+	}
+	if calls != 9 {
+		t.Errorf("progress called %d times, want 9", calls)
+	}
+
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 10 {
+		t.Fatalf("got %d rows, want a header and 9 results:\n%v", len(rows), rows)
+	}
+	// One row per input row and mode, in input and -modes order,
+	// regardless of which pool finished first.
+	for i, want := range [][]string{
+		{"2", goldenUsername, "2", goldenVectors[2], ""},
+		{"2", goldenUsername, "13"},
+		{"2", goldenUsername, "5", goldenVectors[5], ""},
+		{"3", "", "2", "", "skipped: username required"},
+		{"3", "", "13"},
+		{"3", "", "5", goldenVectors[5], ""},
+		{"4", "short", "2"},
+	} {
+		if got := rows[i+1][:len(want)]; !slices.Equal(got, want) {
+			t.Errorf("row %d = %q, want %q", i+1, got, want)
+		}
+	}
+	if ok, err := verifyArgon2(rows[2][3], goldenPassword); !ok || err != nil {
+		t.Errorf("Argon2 result %q does not verify: %v", rows[2][3], err)
+	}
+	if strings.Contains(out.String(), goldenPassword) {
+		t.Error("output contains the password")
+	}
+}
+
+func TestParseModeList(t *testing.T) {
+	if modes, err := parseModeList("1, 5,9,13"); err != nil || !slices.Equal(modes, []int{1, 5, 9, 13}) {
+		t.Errorf("got %v, %v", modes, err)
+	}
+	for _, bad := range []string{"", "1,,2", "1,99", "5,5", "x"} {
+		if _, err := parseModeList(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
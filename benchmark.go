@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Number of hashes benchmarkMode averages over. Argon2 and SCrypt at the
+// loginserver defaults take tens of milliseconds each, so this stays well
+// under a few seconds.
+const benchmarkIterations = 5
+
+// Rough per-hash time worth aiming for on a loginserver host: slow enough to
+// hurt offline cracking, fast enough that logins don't queue up.
+const benchmarkTarget = 250 * time.Millisecond
+
+// benchmarkMode hashes a fixed password iterations times with mode and
+// returns the average time per hash, or ctx.Err() if cancelled.
+func benchmarkMode(ctx context.Context, mode, iterations int) (time.Duration, error) {
+	if iterations < 1 {
+		return 0, fmt.Errorf("iterations must be at least 1, got %d", iterations)
+	}
+	start := time.Now()
+	for i := 0; i < iterations; i++ {
+		if _, err := eqcryptHashCtx(ctx, "benchmark", "benchmark-password", mode); err != nil {
+			return 0, err
+		}
+	}
+	return time.Since(start) / time.Duration(iterations), nil
+}
+
+// benchmarkAdvice compares a measured per-hash time with benchmarkTarget.
+func benchmarkAdvice(perHash time.Duration) string {
+	switch {
+	case perHash < benchmarkTarget/2:
+		return tr("benchmark.under")
+	case perHash > benchmarkTarget*2:
+		return tr("benchmark.over")
+	default:
+		return tr("benchmark.close")
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBenchmarkMode(t *testing.T) {
+	perHash, err := benchmarkMode(context.Background(), 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perHash <= 0 {
+		t.Errorf("got %v per hash, want a positive duration", perHash)
+	}
+
+	if _, err := benchmarkMode(context.Background(), 99, 1); err == nil {
+		t.Error("mode 99: expected an error")
+	}
+	if _, err := benchmarkMode(context.Background(), 1, 0); err == nil {
+		t.Error("0 iterations: expected an error")
+	}
+}
+
+func TestBenchmarkAdvice(t *testing.T) {
+	for _, tc := range []struct {
+		perHash time.Duration
+		want    string
+	}{
+		{10 * time.Millisecond, "well under the ~250ms target, the cost could be raised"},
+		{250 * time.Millisecond, "close to the ~250ms target"},
+		{time.Second, "well over the ~250ms target, logins may be slow"},
+	} {
+		if got := benchmarkAdvice(tc.perHash); got != tc.want {
+			t.Errorf("benchmarkAdvice(%v) = %q, want %q", tc.perHash, got, tc.want)
+		}
+	}
+}
+
+func TestEqcryptHashCtxCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := eqcryptHashCtx(ctx, "", goldenPassword, 14); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled context: got %v, want context.Canceled", err)
+	}
+	if _, err := benchmarkMode(ctx, 13, 5); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled benchmark: got %v, want context.Canceled", err)
+	}
+
+	// A hash that can't be interrupted is abandoned, not waited for.
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	release := make(chan struct{})
+	defer close(release)
+	start := time.Now()
+	_, err := withContext(ctx, func() (string, error) {
+		<-release
+		return "too late", nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second {
+		t.Errorf("got %v after %v, want context.DeadlineExceeded promptly", err, time.Since(start))
+	}
+
+	if hash, err := eqcryptHashCtx(context.Background(), goldenUsername, goldenPassword, 2); err != nil || hash != goldenVectors[2] {
+		t.Errorf("uncancelled: got %q, %v", hash, err)
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Environment variables read when the corresponding flag is absent.
+const (
+	envPassword = "EQHASH_PASSWORD"
+	envUsername = "EQHASH_USERNAME"
+	envMode     = "EQHASH_MODE"
+)
+
+// Exit codes for CLI mode
+const (
+	exitOK    = 0
+	exitError = 1 // hashing failed, or -verify found no match
+	exitUsage = 2 // bad flags or missing input
+)
+
+// cliRequested reports whether the app was started with -cli, -selftest,
+// -list-modes or -serve, in which case it runs headless instead of opening a
+// window.
+func cliRequested(args []string) bool {
+	for _, arg := range args {
+		switch arg {
+		case "-cli", "--cli", "-cli=true", "--cli=true",
+			"-selftest", "--selftest", "-selftest=true", "--selftest=true",
+			"-list-modes", "--list-modes", "-list-modes=true", "--list-modes=true",
+			"-serve", "--serve":
+			return true
+		}
+		if strings.HasPrefix(arg, "-serve=") || strings.HasPrefix(arg, "--serve=") {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyOnlyRequested reports whether the app was started with -verify-only,
+// which the window checks for itself since it doesn't parse flags.
+func verifyOnlyRequested(args []string) bool {
+	for _, arg := range args {
+		switch arg {
+		case "-verify-only", "--verify-only", "-verify-only=true", "--verify-only=true":
+			return true
+		}
+	}
+	return false
+}
+
+// cliResult and cliError are the -json output on success and failure.
+type cliResult struct {
+	Mode     int    `json:"mode"`
+	Username string `json:"username"`
+	Hash     string `json:"hash"`
+	Comment  string `json:"comment,omitempty"` // see commentedOutput
+}
+
+// cliVerifyResult is the -json output of -verify. MatchedMode is the mode the
+// password matched under, which with -mode may not be the selected one.
+type cliVerifyResult struct {
+	Result      string `json:"result"` // PASS or FAIL
+	MatchedMode int    `json:"matched_mode,omitempty"`
+}
+
+// cliMode is one entry of the -list-modes output.
+type cliMode struct {
+	Number        int    `json:"number"`
+	Label         string `json:"label"`
+	NeedsUsername bool   `json:"needs_username"`
+	Family        string `json:"family"` // md5, sha1, sha512, argon2, scrypt, sha256, sha3-256 or sha3-512
+}
+
+// listModes writes modeTable as a JSON array of cliMode, so wrappers can
+// discover the supported modes instead of hardcoding them.
+func listModes(w io.Writer) error {
+	modes := make([]cliMode, len(modeTable))
+	for i, m := range modeTable {
+		modes[i] = cliMode{Number: m.Number, Label: m.Label, NeedsUsername: m.NeedsUsername, Family: m.family()}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(modes)
+}
+
+type cliError struct {
+	Error string `json:"error"`
+}
+
+// readPasswordStdin reads the password for -password-stdin. From a terminal it
+// prompts on stderr and reads one line without echo; otherwise it reads all of
+// stdin, dropping a single trailing newline so `echo secret |` works.
+func readPasswordStdin(stdin io.Reader, stderr io.Writer) (string, error) {
+	if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprint(stderr, "Password: ")
+		password, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(stderr)
+		return string(password), err
+	}
+
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", err
+	}
+	password := strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(password, "\r"), nil
+}
+
+// runCLI hashes a password from the command line, or checks one against
+// -hash with -verify, and returns the process exit code.
+func runCLI(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("eqemu-password-hasher", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Bool("cli", true, "run without the GUI")
+	mode := fs.Int("mode", 14, fmt.Sprintf("encryption mode (1-%d, see -list-modes), or $%s", len(modeTable), envMode))
+	username := fs.String("username", "", "account name, required by the modes that use it, or $"+envUsername)
+	password := fs.String("password", "", "password to hash (visible to other users, prefer -password-stdin or $"+envPassword+")")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from stdin")
+	jsonOut := fs.Bool("json", false, `print {"mode":..,"username":..,"hash":..} or {"error":..} as JSON`)
+	maxLength := fs.Int("max-password-length", defaultMaxPasswordLength, "refuse passwords longer than this many characters, 0 for no limit")
+	saltLength := fs.Int("argon2-salt-length", defaultArgon2SaltLen, "advanced: Argon2 salt length in bytes for non-libsodium verifiers (8-64)")
+	nfc := fs.Bool("nfc", false, "NFC-normalize a non-ASCII username and password before hashing")
+	upper := fs.Bool("uppercase-hex", false, "print MD5/SHA hashes in uppercase hex, for legacy import tools")
+	verify := fs.Bool("verify", false, "check the password against -hash and print PASS or FAIL, exit 1 on FAIL")
+	verifyOnly := fs.Bool("verify-only", false, "refuse everything that produces a hash: only -verify, -selftest and -list-modes run")
+	explain := fs.Bool("explain", false, "debug: hash and print each step (parameters, salt, the encoded salt scrypt is given) before the hash")
+	storedHash := fs.String("hash", "", "stored hash for -verify; without -mode, every mode that fits its format is tried")
+	batchFile := fs.String("batch", "", `hash each username,password row of a CSV file ("-" for stdin), printing a CSV of hashes`)
+	modesFlag := fs.String("modes", "", "comma-separated modes for -batch, e.g. 1,5,9,13, one output row per mode (default -mode)")
+	selfTest := fs.Bool("selftest", false, "hash and verify a fixed password in every mode, exit 1 if any mode fails")
+	configFile := fs.String("config", "", "defaults file (default: "+appConfigName+" next to the binary, then in the user config directory)")
+	serve := fs.String("serve", "", `run an HTTP API on this address (":8080" listens on localhost only): POST /hash and POST /verify`)
+	serveToken := fs.String("serve-token", "", "token -serve requires as \"Authorization: Bearer <token>\", or $"+envServeToken)
+	listModesFlag := fs.Bool("list-modes", false, "print the supported modes as JSON: number, label, needs_username, family")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	// config.json supplies defaults; flags and the environment win.
+	configPaths := appConfigPaths()
+	if *configFile != "" {
+		configPaths = []string{*configFile}
+	}
+	cfg, path, err := loadAppConfig(configPaths)
+	if err == nil && *configFile != "" && path == "" {
+		err = fmt.Errorf("%s not found", *configFile)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "error: config:", err)
+		return exitUsage
+	}
+	cfg.applyKDFParams()
+	if cfg.MaxPasswordLength != nil && !given["max-password-length"] {
+		*maxLength = *cfg.MaxPasswordLength
+	}
+	if cfg.Argon2SaltLength != nil && !given["argon2-salt-length"] {
+		*saltLength = *cfg.Argon2SaltLength
+	}
+	if cfg.NormalizeUnicode != nil && !given["nfc"] {
+		*nfc = *cfg.NormalizeUnicode
+	}
+	if cfg.VerifyOnly != nil && *cfg.VerifyOnly {
+		// The file can only lock the CLI down, never lift a -verify-only.
+		*verifyOnly = true
+	}
+
+	if *maxLength < 0 {
+		fmt.Fprintln(stderr, "error: -max-password-length must not be negative")
+		return exitUsage
+	}
+	setMaxPasswordLength(*maxLength)
+	if err := checkArgon2SaltLen(*saltLength); err != nil {
+		fmt.Fprintln(stderr, "error: -argon2-salt-length:", err)
+		return exitUsage
+	}
+	setArgon2SaltLen(*saltLength)
+	setUppercaseHex(*upper)
+
+	if *listModesFlag {
+		if err := listModes(stdout); err != nil {
+			fmt.Fprintln(stderr, "error:", err)
+			return exitError
+		}
+		return exitOK
+	}
+
+	if *verifyOnly && (*serve != "" || *batchFile != "" || *explain || !*verify && !*selfTest) {
+		fmt.Fprintln(stderr, "error: -verify-only allows only -verify, -selftest and -list-modes")
+		return exitUsage
+	}
+
+	if *serve != "" {
+		token := *serveToken
+		if token == "" {
+			token = os.Getenv(envServeToken)
+		}
+		return runServe(*serve, token, stderr)
+	}
+
+	if *selfTest {
+		if failed := runSelfTest(stdout); failed > 0 {
+			fmt.Fprintf(stderr, "self-test: %d of %d modes failed\n", failed, len(modeTable))
+			return exitError
+		}
+		fmt.Fprintf(stdout, "self-test: all %d modes passed\n", len(modeTable))
+		return exitOK
+	}
+
+	// fail reports an error as plain text on stderr, or as JSON on stdout.
+	fail := func(code int, format string, a ...any) int {
+		msg := fmt.Sprintf(format, a...)
+		if *jsonOut {
+			json.NewEncoder(stdout).Encode(cliError{Error: msg})
+		} else {
+			fmt.Fprintln(stderr, "error:", msg)
+		}
+		return code
+	}
+
+	// Environment fallbacks for pipelines that inject secrets as variables.
+	// Flags win over the environment.
+	if !given["mode"] {
+		if value, ok := os.LookupEnv(envMode); ok {
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return fail(exitUsage, "invalid %s %q", envMode, value)
+			}
+			*mode = n
+			given["mode"] = true
+		} else if cfg.Mode != nil {
+			// A configured default is not an explicit choice: -verify
+			// still tries every mode that fits the hash.
+			*mode = *cfg.Mode
+		}
+	}
+	if !given["username"] {
+		if value, ok := os.LookupEnv(envUsername); ok {
+			*username = value
+		}
+	}
+	if *batchFile != "" {
+		modes := []int{*mode}
+		if *modesFlag != "" {
+			var err error
+			if modes, err = parseModeList(*modesFlag); err != nil {
+				return fail(exitUsage, "-modes: %v", err)
+			}
+		} else if _, ok := lookupMode(*mode); !ok {
+			return fail(exitUsage, "unsupported encryption mode: %d", *mode)
+		}
+		return cliBatch(*batchFile, modes, stdin, stdout, stderr, fail)
+	}
+
+	passwordFromEnv := false
+	if !given["password"] && !*passwordStdin {
+		*password, passwordFromEnv = os.LookupEnv(envPassword)
+	}
+
+	switch {
+	case *passwordStdin && *password != "":
+		return fail(exitUsage, "use either -password or -password-stdin, not both")
+	case *passwordStdin:
+		var err error
+		if *password, err = readPasswordStdin(stdin, stderr); err != nil {
+			return fail(exitError, "reading password: %v", err)
+		}
+	case *password != "" && !passwordFromEnv:
+		fmt.Fprintln(stderr, "warning: -password exposes the password in the process list and shell history, consider -password-stdin")
+	}
+	if *password == "" {
+		return fail(exitUsage, "a password is required (-password-stdin, -password or %s)", envPassword)
+	}
+	// A configured mode doesn't apply to -verify, see above.
+	if modeNeedsUsername[*mode] && *username == "" && (!*verify || given["mode"]) {
+		return fail(exitUsage, "mode %d requires -username", *mode)
+	}
+	var nonASCII, usernameNonASCII bool
+	*password, nonASCII = normalizeInput(*password, *nfc)
+	*username, usernameNonASCII = normalizeInput(*username, *nfc)
+	if (nonASCII || usernameNonASCII && modeNeedsUsername[*mode]) && !*nfc {
+		fmt.Fprintln(stderr, "warning: input has non-ASCII characters and is hashed as given; loginserver does not normalize, see -nfc")
+	}
+
+	if *verify {
+		// Without an explicit mode, detect it like the Verify tab does.
+		selected := 0
+		if given["mode"] {
+			selected = *mode
+		}
+		hash, junk := cleanHash(*storedHash)
+		if junk > 0 {
+			fmt.Fprintf(stderr, "warning: removed %d whitespace or control characters from inside -hash\n", junk)
+		}
+		return cliVerify(hash, *username, *password, selected, *jsonOut, stdout, stderr, fail)
+	}
+
+	if *explain {
+		if *jsonOut {
+			return fail(exitUsage, "-explain has no JSON output")
+		}
+		if err := explainHash(stdout, *username, *password, *mode, nil); err != nil {
+			return fail(exitError, "%v", err)
+		}
+		return exitOK
+	}
+
+	hash, err := eqcryptHash(*username, *password, *mode)
+	if err != nil {
+		return fail(exitError, "%v", err)
+	}
+
+	if *jsonOut {
+		json.NewEncoder(stdout).Encode(cliResult{Mode: *mode, Username: *username, Hash: outputHash(hash)})
+	} else {
+		fmt.Fprintln(stdout, outputHash(hash))
+	}
+	return exitOK
+}
+
+// cliVerify implements -verify. With a selected mode, only that mode counts
+// as a PASS, but a match under another mode is pointed out on stderr.
+func cliVerify(storedHash, username, password string, selected int, jsonOut bool, stdout, stderr io.Writer, fail func(int, string, ...any) int) int {
+	if storedHash == "" {
+		return fail(exitUsage, "-verify requires -hash")
+	}
+	matched, err := verifySelected(storedHash, username, password, selected)
+	if err != nil {
+		return fail(exitError, "%v", err)
+	}
+
+	pass := matched != 0 && (selected == 0 || matched == selected)
+	if matched != 0 && !pass {
+		fmt.Fprintf(stderr, "note: password matches under mode %d (%s), not the selected mode %d\n", matched, modeName(matched), selected)
+	}
+	if skipped := skippedModes(storedHash, username); !pass && len(skipped) > 0 {
+		fmt.Fprintf(stderr, "note: modes %s need -username and were not tried\n", modeList(skipped))
+	}
+	if matched == 0 && strings.HasPrefix(storedHash, "$7$") {
+		fmt.Fprintln(stderr, "note: the SCrypt hash is well-formed; the password derives a different key")
+	}
+
+	result := "FAIL"
+	if pass {
+		result = "PASS"
+	}
+	if jsonOut {
+		json.NewEncoder(stdout).Encode(cliVerifyResult{Result: result, MatchedMode: matched})
+	} else {
+		fmt.Fprintln(stdout, result)
+	}
+	if !pass {
+		return exitError
+	}
+	return exitOK
+}
+
+// cliBatch implements -batch, reading the CSV from path or, for "-", stdin.
+func cliBatch(path string, modes []int, stdin io.Reader, stdout, stderr io.Writer, fail func(int, string, ...any) int) int {
+	in := stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fail(exitUsage, "%v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	summary, err := batchHash(in, stdout, modes, nil)
+	if err != nil {
+		return fail(exitError, "%v", err)
+	}
+	fmt.Fprintf(stderr, "batch: %v\n", summary)
+	if summary.Errors > 0 {
+		return exitError
+	}
+	return exitOK
+}
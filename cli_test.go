@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCLIRequested(t *testing.T) {
+	if cliRequested(nil) || cliRequested([]string{"-psn_0_12345"}) {
+		t.Error("GUI launch detected as CLI")
+	}
+	if !cliRequested([]string{"-mode", "5", "-cli"}) || !cliRequested([]string{"--cli"}) {
+		t.Error("-cli not detected")
+	}
+	if !cliRequested([]string{"-list-modes"}) {
+		t.Error("-list-modes not detected")
+	}
+	if !cliRequested([]string{"-serve", ":8080"}) || !cliRequested([]string{"--serve=:8080"}) {
+		t.Error("-serve not detected")
+	}
+	if cliRequested([]string{"-serve-token", "x"}) {
+		t.Error("-serve-token alone detected as CLI")
+	}
+}
+
+func TestVerifyOnlyRequested(t *testing.T) {
+	if verifyOnlyRequested(nil) || verifyOnlyRequested([]string{"-verify"}) {
+		t.Error("verify-only detected without the flag")
+	}
+	if !verifyOnlyRequested([]string{"-verify-only"}) || !verifyOnlyRequested([]string{"--verify-only=true"}) {
+		t.Error("-verify-only not detected")
+	}
+}
+
+func TestRunCLIVerifyOnly(t *testing.T) {
+	refused := [][]string{
+		{"-cli", "-verify-only", "-mode", "1", "-password", goldenPassword},
+		{"-cli", "-verify-only", "-verify", "-batch", "-"},
+		{"-cli", "-verify-only", "-explain", "-password", goldenPassword},
+		{"-verify-only", "-verify", "-serve", ":0"},
+	}
+	for _, args := range refused {
+		var stdout, stderr bytes.Buffer
+		if code := runCLI(args, strings.NewReader(""), &stdout, &stderr); code != exitUsage {
+			t.Errorf("%v: exit code %d, want %d", args, code, exitUsage)
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("%v: printed %q", args, stdout.String())
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"-cli", "-verify-only", "-verify", "-hash", goldenVectors[1], "-password", goldenPassword}
+	if code := runCLI(args, strings.NewReader(""), &stdout, &stderr); code != exitOK {
+		t.Errorf("-verify: exit code %d, stderr: %s", code, stderr.String())
+	}
+}
+
+func TestRunCLI(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-cli", "-mode", "2", "-username", goldenUsername, "-password", goldenPassword}, strings.NewReader(""), &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if got := strings.TrimSpace(stdout.String()); got != goldenVectors[2] {
+		t.Errorf("got %q, want %q", got, goldenVectors[2])
+	}
+}
+
+func TestRunCLIJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-cli", "-json", "-mode", "1", "-password", goldenPassword}, strings.NewReader(""), &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	var result cliResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout.String(), err)
+	}
+	if result.Mode != 1 || result.Hash != goldenVectors[1] {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	stdout.Reset()
+	code = runCLI([]string{"-cli", "-json", "-mode", "99", "-password", goldenPassword}, strings.NewReader(""), &stdout, &stderr)
+	if code != exitError {
+		t.Errorf("exit code %d, want %d", code, exitError)
+	}
+	if !strings.HasPrefix(stdout.String(), `{"error":"unsupported encryption mode: 99"}`) {
+		t.Errorf("unexpected error JSON %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := runCLI([]string{"-cli", "-json", "-mode", "3", "-password", "x"}, strings.NewReader(""), &stdout, &stderr); code != exitUsage {
+		t.Errorf("missing username: exit code %d, want %d", code, exitUsage)
+	}
+}
+
+func TestRunCLIPasswordStdin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	stdin := strings.NewReader(goldenPassword + "\r\n")
+	code := runCLI([]string{"-cli", "-mode", "5", "-password-stdin"}, stdin, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if got := strings.TrimSpace(stdout.String()); got != goldenVectors[5] {
+		t.Errorf("got %q, want %q", got, goldenVectors[5])
+	}
+	if strings.Contains(stderr.String(), "warning") {
+		t.Errorf("unexpected warning for -password-stdin: %s", stderr.String())
+	}
+
+	stderr.Reset()
+	runCLI([]string{"-cli", "-mode", "5", "-password", goldenPassword}, strings.NewReader(""), &stdout, &stderr)
+	if !strings.Contains(stderr.String(), "warning: -password exposes") {
+		t.Errorf("expected an exposure warning for -password, got %q", stderr.String())
+	}
+
+	code = runCLI([]string{"-cli", "-password-stdin", "-password", "x"}, strings.NewReader("y"), &stdout, &stderr)
+	if code != exitUsage {
+		t.Errorf("both password flags: exit code %d, want %d", code, exitUsage)
+	}
+}
+
+func TestRunCLINFC(t *testing.T) {
+	composed, decomposed := "caf\u00e9", "cafe\u0301"
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-cli", "-mode", "5", "-password", decomposed}, strings.NewReader(""), &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if got := strings.TrimSpace(stdout.String()); got != hashSHA1(decomposed) {
+		t.Errorf("without -nfc: got %q, want the hash of the input as given", got)
+	}
+	if !strings.Contains(stderr.String(), "non-ASCII") {
+		t.Errorf("no non-ASCII warning on stderr: %q", stderr.String())
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	code = runCLI([]string{"-cli", "-nfc", "-mode", "5", "-password", decomposed}, strings.NewReader(""), &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if got := strings.TrimSpace(stdout.String()); got != hashSHA1(composed) {
+		t.Errorf("with -nfc: got %q, want the hash of the NFC form", got)
+	}
+}
+
+func TestRunCLIMaxPasswordLength(t *testing.T) {
+	defer func(old int) { maxPasswordLength = old }(maxPasswordLength)
+
+	var stdout, stderr bytes.Buffer
+	long := strings.Repeat("x", 2000)
+	if code := runCLI([]string{"-cli", "-mode", "5", "-password", long}, strings.NewReader(""), &stdout, &stderr); code != exitError {
+		t.Errorf("over the default limit: exit code %d, want %d", code, exitError)
+	}
+	stdout.Reset()
+	code := runCLI([]string{"-cli", "-mode", "5", "-max-password-length", "0", "-password", long}, strings.NewReader(""), &stdout, &stderr)
+	if code != exitOK || strings.TrimSpace(stdout.String()) != hashSHA1(long) {
+		t.Errorf("-max-password-length 0: exit code %d, output %q", code, stdout.String())
+	}
+}
+
+func TestRunCLIUppercaseHex(t *testing.T) {
+	defer func() { uppercaseHex = false }()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-cli", "-uppercase-hex", "-mode", "5", "-password", goldenPassword}, strings.NewReader(""), &stdout, &stderr)
+	upper := strings.ToUpper(goldenVectors[5])
+	if got := strings.TrimSpace(stdout.String()); code != exitOK || got != upper {
+		t.Errorf("exit code %d, got %q, want %q", code, got, upper)
+	}
+	stdout.Reset()
+	code = runCLI([]string{"-cli", "-verify", "-hash", upper, "-password", goldenPassword}, strings.NewReader(""), &stdout, &stderr)
+	if code != exitOK || strings.TrimSpace(stdout.String()) != "PASS" {
+		t.Errorf("-verify of the uppercase hash: exit code %d, output %q", code, stdout.String())
+	}
+}
+
+func TestRunCLIArgon2SaltLength(t *testing.T) {
+	defer func(old int) { argon2SaltLen = old }(argon2SaltLen)
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-cli", "-mode", "13", "-argon2-salt-length", "32", "-password", goldenPassword}, strings.NewReader(""), &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	hash := strings.TrimSpace(stdout.String())
+	if _, salt, _, err := parseArgon2PHC(hash); err != nil || len(salt) != 32 {
+		t.Errorf("hash %q: %d-byte salt, %v", hash, len(salt), err)
+	}
+	if ok, err := verifyArgon2(hash, goldenPassword); !ok || err != nil {
+		t.Errorf("32-byte salt hash does not verify: %v", err)
+	}
+
+	for _, bad := range []string{"4", "65"} {
+		if code := runCLI([]string{"-cli", "-mode", "13", "-argon2-salt-length", bad, "-password", goldenPassword}, strings.NewReader(""), &stdout, &stderr); code != exitUsage {
+			t.Errorf("-argon2-salt-length %s: exit code %d, want %d", bad, code, exitUsage)
+		}
+	}
+}
+
+func TestRunCLIVerify(t *testing.T) {
+	run := func(args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		code := runCLI(append([]string{"-cli", "-verify"}, args...), strings.NewReader(""), &stdout, &stderr)
+		return code, strings.TrimSpace(stdout.String()), stderr.String()
+	}
+
+	if code, out, _ := run("-hash", goldenVectors[14], "-password", goldenPassword); code != exitOK || out != "PASS" {
+		t.Errorf("SCrypt: exit code %d, output %q", code, out)
+	}
+	if code, out, stderr := run("-hash", goldenVectors[14], "-password", "wrong"); code != exitError || out != "FAIL" || !strings.Contains(stderr, "derives a different key") {
+		t.Errorf("wrong password: exit code %d, output %q, stderr %q", code, out, stderr)
+	}
+	if code, out, _ := run("-hash", goldenVectors[7], "-username", goldenUsername, "-password", goldenPassword); code != exitOK || out != "PASS" {
+		t.Errorf("detected mode 7: exit code %d, output %q", code, out)
+	}
+
+	code, out, stderr := run("-hash", goldenVectors[7], "-mode", "6", "-username", goldenUsername, "-password", goldenPassword)
+	if code != exitError || out != "FAIL" || !strings.Contains(stderr, "matches under mode 7") {
+		t.Errorf("wrong -mode: exit code %d, output %q, stderr %q", code, out, stderr)
+	}
+
+	code, out, stderr = run("-hash", goldenVectors[7], "-password", goldenPassword)
+	if code != exitError || !strings.Contains(stderr, "modes 6, 7, 8 need -username") {
+		t.Errorf("no username: exit code %d, output %q, stderr %q", code, out, stderr)
+	}
+
+	code, out, _ = run("-json", "-hash", goldenVectors[7], "-mode", "6", "-username", goldenUsername, "-password", goldenPassword)
+	var result cliVerifyResult
+	if err := json.Unmarshal([]byte(out), &result); err != nil || result != (cliVerifyResult{Result: "FAIL", MatchedMode: 7}) {
+		t.Errorf("JSON: exit code %d, output %q, %v", code, out, err)
+	}
+
+	broken := goldenVectors[14][:30] + "\r" + goldenVectors[14][30:]
+	if code, out, stderr := run("-hash", broken, "-password", goldenPassword); code != exitOK || out != "PASS" || !strings.Contains(stderr, "removed 1 whitespace") {
+		t.Errorf("embedded CR: exit code %d, output %q, stderr %q", code, out, stderr)
+	}
+
+	if code, _, _ := run("-password", goldenPassword); code != exitUsage {
+		t.Errorf("missing -hash: exit code %d, want %d", code, exitUsage)
+	}
+	if code, _, _ := run("-hash", "$7$garbage", "-password", goldenPassword); code != exitError {
+		t.Errorf("malformed hash: exit code %d, want %d", code, exitError)
+	}
+}
+
+func TestRunCLIEnvironment(t *testing.T) {
+	t.Setenv(envPassword, goldenPassword)
+	t.Setenv(envUsername, goldenUsername)
+	t.Setenv(envMode, "2")
+
+	var stdout, stderr bytes.Buffer
+	if code := runCLI([]string{"-cli"}, strings.NewReader(""), &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	if got := strings.TrimSpace(stdout.String()); got != goldenVectors[2] {
+		t.Errorf("got %q, want %q", got, goldenVectors[2])
+	}
+	if strings.Contains(stderr.String(), "process list") {
+		t.Errorf("warned about a password that came from the environment: %s", stderr.String())
+	}
+
+	// Flags override the environment.
+	stdout.Reset()
+	code := runCLI([]string{"-cli", "-mode", "3", "-username", "Other"}, strings.NewReader(""), &stdout, &stderr)
+	want, _ := eqcryptHash("Other", goldenPassword, 3)
+	if code != exitOK || strings.TrimSpace(stdout.String()) != want {
+		t.Errorf("flags over environment: exit code %d, got %q, want %q", code, stdout.String(), want)
+	}
+
+	// EQHASH_MODE selects the mode for -verify like -mode does.
+	stdout.Reset()
+	code = runCLI([]string{"-cli", "-verify", "-hash", goldenVectors[3]}, strings.NewReader(""), &stdout, &stderr)
+	if code != exitError || strings.TrimSpace(stdout.String()) != "FAIL" {
+		t.Errorf("verify with %s=2: exit code %d, output %q", envMode, code, stdout.String())
+	}
+
+	t.Setenv(envMode, "two")
+	if code := runCLI([]string{"-cli"}, strings.NewReader(""), &stdout, &stderr); code != exitUsage {
+		t.Errorf("invalid %s: exit code %d, want %d", envMode, code, exitUsage)
+	}
+}
+
+func TestRunCLIBatch(t *testing.T) {
+	input := "username,password\n" + goldenUsername + "," + goldenPassword + "\n"
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-cli", "-batch", "-", "-modes", "1,6"}, strings.NewReader(input), &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	want := "line,username,mode,hash,error\n" +
+		"2," + goldenUsername + ",1," + goldenVectors[1] + ",\n" +
+		"2," + goldenUsername + ",6," + goldenVectors[6] + ",\n"
+	if stdout.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", stdout.String(), want)
+	}
+
+	if !strings.Contains(stderr.String(), "processed 2 hashes for 1 rows in ") || !strings.Contains(stderr.String(), ": 2 succeeded, 0 failed") {
+		t.Errorf("stderr = %q, want a summary of the run", stderr.String())
+	}
+
+	if code := runCLI([]string{"-cli", "-batch", "-", "-modes", "1,99"}, strings.NewReader(input), &stdout, &stderr); code != exitUsage {
+		t.Errorf("bad -modes: exit code %d, want %d", code, exitUsage)
+	}
+	if code := runCLI([]string{"-cli", "-batch", "-", "-modes", "2"}, strings.NewReader("nouser\n"), &stdout, &stderr); code != exitError {
+		t.Errorf("row errors: exit code %d, want %d", code, exitError)
+	}
+}
+
+func TestRunCLIListModes(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runCLI([]string{"-list-modes"}, strings.NewReader(""), &stdout, &stderr); code != exitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	var modes []cliMode
+	if err := json.Unmarshal(stdout.Bytes(), &modes); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout.String(), err)
+	}
+	if len(modes) != len(modeTable) {
+		t.Fatalf("%d modes, want %d", len(modes), len(modeTable))
+	}
+	if want := (cliMode{Number: 6, Label: "SHA1 (password:username)", NeedsUsername: true, Family: "sha1"}); modes[5] != want {
+		t.Errorf("mode 6: got %+v, want %+v", modes[5], want)
+	}
+	for _, m := range modes {
+		if m.Number == 13 && m.Family != "argon2" || m.Number == 18 && m.Family != "sha256" {
+			t.Errorf("mode %d: family %q", m.Number, m.Family)
+		}
+	}
+}
+
+func TestRunCLIModeHelp(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	runCLI([]string{"-cli", "-h"}, strings.NewReader(""), &stdout, &stderr)
+	if want := fmt.Sprintf("(1-%d, see -list-modes)", len(modeTable)); !strings.Contains(stderr.String(), want) {
+		t.Errorf("-mode help doesn't say %q:\n%s", want, stderr.String())
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import "errors"
+
+// clipboard is the part of fyne.Clipboard used here, so the checks below
+// work without a window.
+type clipboard interface {
+	Content() string
+	SetContent(content string)
+}
+
+// On headless machines and some forwarded displays the platform clipboard
+// doesn't work: setting it only logs an error, reading returns "", and some
+// drivers panic instead.
+var errClipboardUnavailable = errors.New("clipboard unavailable")
+
+// clipboardContent is cb.Content with a panic reported as
+// errClipboardUnavailable.
+func clipboardContent(cb clipboard) (text string, err error) {
+	defer func() {
+		if recover() != nil {
+			err = errClipboardUnavailable
+		}
+	}()
+	return cb.Content(), nil
+}
+
+// setClipboardContent is cb.SetContent with a panic reported as
+// errClipboardUnavailable.
+func setClipboardContent(cb clipboard, text string) (err error) {
+	defer func() {
+		if recover() != nil {
+			err = errClipboardUnavailable
+		}
+	}()
+	cb.SetContent(text)
+	return nil
+}
+
+// writeClipboard copies text and reads it back, so a copy that silently went
+// nowhere isn't reported as done.
+func writeClipboard(cb clipboard, text string) error {
+	if err := setClipboardContent(cb, text); err != nil {
+		return err
+	}
+	if content, err := clipboardContent(cb); err != nil || content != text {
+		return errClipboardUnavailable
+	}
+	return nil
+}
+
+// readClipboard returns the clipboard's text. An empty clipboard is probed by
+// writing to it, which loses nothing, to tell "nothing copied" from a
+// clipboard that doesn't work.
+func readClipboard(cb clipboard) (string, error) {
+	text, err := clipboardContent(cb)
+	if err != nil || text != "" {
+		return text, err
+	}
+	if err := writeClipboard(cb, "eqemu-password-hasher"); err != nil {
+		return "", err
+	}
+	return "", setClipboardContent(cb, "")
+}
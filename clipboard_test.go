@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+type memoryClipboard struct{ text string }
+
+func (c *memoryClipboard) Content() string           { return c.text }
+func (c *memoryClipboard) SetContent(content string) { c.text = content }
+
+// deadClipboard is a clipboard without a display: writes go nowhere.
+type deadClipboard struct{}
+
+func (deadClipboard) Content() string   { return "" }
+func (deadClipboard) SetContent(string) {}
+
+type panickyClipboard struct{}
+
+func (panickyClipboard) Content() string   { panic("no display") }
+func (panickyClipboard) SetContent(string) { panic("no display") }
+
+func TestWriteClipboard(t *testing.T) {
+	cb := &memoryClipboard{}
+	if err := writeClipboard(cb, "hash"); err != nil || cb.text != "hash" {
+		t.Errorf("working clipboard: %q, %v", cb.text, err)
+	}
+	for _, cb := range []clipboard{deadClipboard{}, panickyClipboard{}} {
+		if err := writeClipboard(cb, "hash"); !errors.Is(err, errClipboardUnavailable) {
+			t.Errorf("%T: got %v", cb, err)
+		}
+	}
+}
+
+func TestReadClipboard(t *testing.T) {
+	cb := &memoryClipboard{text: "hash"}
+	if text, err := readClipboard(cb); text != "hash" || err != nil {
+		t.Errorf("got %q, %v", text, err)
+	}
+	// An empty clipboard is probed and left empty.
+	cb.text = ""
+	if text, err := readClipboard(cb); text != "" || err != nil || cb.text != "" {
+		t.Errorf("empty clipboard: got %q, %v, left %q", text, err, cb.text)
+	}
+	for _, cb := range []clipboard{deadClipboard{}, panickyClipboard{}} {
+		if _, err := readClipboard(cb); !errors.Is(err, errClipboardUnavailable) {
+			t.Errorf("%T: got %v", cb, err)
+		}
+	}
+}
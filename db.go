@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL's ER_DUP_ENTRY, raised when an insert hits a unique key.
+const mysqlErrDuplicateEntry = 1062
+
+var errAccountExists = errors.New("account already exists")
+
+// How long a single database operation may take before it is abandoned.
+const dbTimeout = 15 * time.Second
+
+// dbConfig holds the MySQL connection details for the loginserver database.
+type dbConfig struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Database string
+}
+
+func (c dbConfig) dsn() string {
+	cfg := mysql.NewConfig()
+	cfg.User = c.User
+	cfg.Passwd = c.Password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	cfg.DBName = c.Database
+	cfg.Timeout = dbTimeout
+	// Report matched rather than changed rows, so re-setting the same hash
+	// still counts as finding the account.
+	cfg.ClientFoundRows = true
+	return cfg.FormatDSN()
+}
+
+func openDB(cfg dbConfig) (*sql.DB, error) {
+	return sql.Open("mysql", cfg.dsn())
+}
+
+// How long Test Connection waits, so a wrong host fails in seconds rather
+// than after dbTimeout.
+const dbPingTimeout = 5 * time.Second
+
+// pingDB connects and runs SELECT 1, to check the connection details before
+// anything is written.
+func pingDB(ctx context.Context, cfg dbConfig) error {
+	ctx, cancel := context.WithTimeout(ctx, dbPingTimeout)
+	defer cancel()
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var one int
+	return db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+}
+
+// accountTarget selects which loginserver table a hash is meant for. Both
+// tables store account_password in the same format, so only the destination
+// differs.
+type accountTarget int
+
+const (
+	targetAccount accountTarget = iota // login_accounts, player logins
+	targetAdmin                        // login_server_admins, world server admins
+)
+
+// accountTargetOptions returns the labels for the target selector, indexed by
+// accountTarget.
+func accountTargetOptions() []string {
+	return []string{tr("target.account"), tr("target.admin")}
+}
+
+func (t accountTarget) table() string {
+	if t == targetAdmin {
+		return "login_server_admins"
+	}
+	return "login_accounts"
+}
+
+// noun names a row of the target table in status messages.
+func (t accountTarget) noun() string {
+	if t == targetAdmin {
+		return tr("target.noun.admin")
+	}
+	return tr("target.noun.account")
+}
+
+// checkDBWrite checks what the Database panel is about to store for account:
+// hash must be in the format of mode, the mode it was generated with, and
+// for the modes that hash the username, account must be the name it was
+// generated for, or that account could never log in with it.
+func checkDBWrite(mode int, hash, generatedFor, account string) error {
+	if !slices.Contains(detectHashModes(hash), mode) {
+		return errors.New(tr("db.not_a_hash", mode, modeName(mode)))
+	}
+	var err error
+	switch mode {
+	case 13:
+		_, _, _, err = parseArgon2PHC(hash)
+	case 14:
+		_, _, _, err = parseSCryptMCF(hash)
+	}
+	if err != nil {
+		return errors.New(tr("db.invalid_hash", mode, err))
+	}
+	if modeNeedsUsername[mode] && account != generatedFor {
+		return errors.New(tr("db.account_mismatch", mode, generatedFor, account))
+	}
+	return nil
+}
+
+// updateAccountPassword sets account_password for the named account in the
+// target table and returns the number of rows matched.
+func updateAccountPassword(ctx context.Context, db *sql.DB, target accountTarget, accountName, hash string) (int64, error) {
+	res, err := db.ExecContext(ctx,
+		"UPDATE "+target.table()+" SET account_password = ? WHERE account_name = ?",
+		hash, accountName)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// newAccount describes a login_accounts or login_server_admins row to be
+// created.
+type newAccount struct {
+	Name   string
+	Hash   string
+	Email  string
+	Source string // source_loginserver, "local" for accounts created here; not used for admins
+}
+
+// createAccount inserts a new row into the target table and returns its id.
+// It returns errAccountExists if the account name is already taken.
+// login_server_admins has no unique key on account_name, so that is checked
+// up front rather than relying on the insert failing.
+func createAccount(ctx context.Context, db *sql.DB, target accountTarget, acct newAccount) (int64, error) {
+	var existing int
+	err := db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM "+target.table()+" WHERE account_name = ?", acct.Name).Scan(&existing)
+	if err != nil {
+		return 0, err
+	}
+	if existing > 0 {
+		return 0, fmt.Errorf("%w: %s", errAccountExists, acct.Name)
+	}
+
+	var res sql.Result
+	if target == targetAdmin {
+		res, err = db.ExecContext(ctx,
+			`INSERT INTO login_server_admins
+				(account_name, account_password, first_name, last_name, email,
+				 registration_date, registration_ip_address)
+			 VALUES (?, ?, '', '', ?, NOW(), '')`,
+			acct.Name, acct.Hash, acct.Email)
+	} else {
+		res, err = db.ExecContext(ctx,
+			`INSERT INTO login_accounts
+				(account_name, account_password, account_email, source_loginserver,
+				 last_ip_address, last_login_date, created_at)
+			 VALUES (?, ?, ?, ?, '', NOW(), NOW())`,
+			acct.Name, acct.Hash, acct.Email, acct.Source)
+	}
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+			return 0, fmt.Errorf("%w: %s", errAccountExists, acct.Name)
+		}
+		return 0, err
+	}
+	return res.LastInsertId()
+}
@@ -0,0 +1,233 @@
+//go:build !cli
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Preferences keys for the Database panel. Connection details are only
+// written when the user ticks "Remember connection details".
+const (
+	prefDBRemember = "dbRemember"
+	prefDBHost     = "dbHost"
+	prefDBPort     = "dbPort"
+	prefDBUser     = "dbUser"
+	prefDBPassword = "dbPassword"
+	prefDBName     = "dbName"
+)
+
+// buildDatabasePanel builds the optional panel that writes the generated hash
+// straight into the table chosen by target. generated returns the last
+// generated hash, raw rather than formatted so SQL or JSON never ends up
+// stored as a password, with its mode and username.
+func buildDatabasePanel(w fyne.Window, statusLabel *widget.Label, prefs fyne.Preferences, generated func() historyEntry, target func() accountTarget) fyne.CanvasObject {
+	remember := prefs.Bool(prefDBRemember)
+
+	hostEntry := widget.NewEntry()
+	hostEntry.SetText("127.0.0.1")
+	portEntry := widget.NewEntry()
+	portEntry.SetText("3306")
+	userEntry := widget.NewEntry()
+	passwordEntry := widget.NewPasswordEntry()
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText("peq")
+
+	if remember {
+		hostEntry.SetText(prefs.StringWithFallback(prefDBHost, hostEntry.Text))
+		portEntry.SetText(prefs.StringWithFallback(prefDBPort, portEntry.Text))
+		userEntry.SetText(prefs.String(prefDBUser))
+		passwordEntry.SetText(prefs.String(prefDBPassword))
+		nameEntry.SetText(prefs.StringWithFallback(prefDBName, nameEntry.Text))
+	}
+
+	rememberCheck := widget.NewCheck(tr("db.remember"), func(on bool) {
+		prefs.SetBool(prefDBRemember, on)
+		if !on {
+			for _, key := range []string{prefDBHost, prefDBPort, prefDBUser, prefDBPassword, prefDBName} {
+				prefs.RemoveValue(key)
+			}
+		}
+	})
+	rememberCheck.SetChecked(remember)
+
+	accountEntry := widget.NewEntry()
+	accountEntry.SetPlaceHolder("account_name")
+	emailEntry := widget.NewEntry()
+	emailEntry.SetPlaceHolder(tr("db.email_placeholder"))
+	sourceEntry := widget.NewEntry()
+	sourceEntry.SetText("local")
+
+	// connection reads and validates the connection fields, saving them if
+	// the user opted in.
+	connection := func() (dbConfig, error) {
+		port, err := strconv.Atoi(strings.TrimSpace(portEntry.Text))
+		if err != nil || port < 1 || port > 65535 {
+			return dbConfig{}, errors.New(tr("db.invalid_port", portEntry.Text))
+		}
+		cfg := dbConfig{
+			Host:     strings.TrimSpace(hostEntry.Text),
+			Port:     port,
+			User:     strings.TrimSpace(userEntry.Text),
+			Password: passwordEntry.Text,
+			Database: strings.TrimSpace(nameEntry.Text),
+		}
+		if cfg.Host == "" || cfg.User == "" || cfg.Database == "" {
+			return dbConfig{}, errors.New(tr("db.required_fields"))
+		}
+
+		if rememberCheck.Checked {
+			prefs.SetString(prefDBHost, cfg.Host)
+			prefs.SetString(prefDBPort, strconv.Itoa(cfg.Port))
+			prefs.SetString(prefDBUser, cfg.User)
+			prefs.SetString(prefDBPassword, cfg.Password)
+			prefs.SetString(prefDBName, cfg.Database)
+		}
+		return cfg, nil
+	}
+
+	var testButton, updateButton, createButton *widget.Button
+	testButton = widget.NewButton(tr("db.test"), func() {
+		cfg, err := connection()
+		if err != nil {
+			statusLabel.SetText(tr("error", err))
+			return
+		}
+		testButton.Disable()
+		statusLabel.SetText(tr("db.testing", cfg.Host, cfg.Port))
+		go func() {
+			defer testButton.Enable()
+			defer recoverPanic(showPanic(statusLabel, nil))
+			if err := pingDB(context.Background(), cfg); err != nil {
+				statusLabel.SetText(tr("error.database", err))
+				return
+			}
+			statusLabel.SetText(tr("db.test_ok", cfg.Host, cfg.Port, cfg.User, cfg.Database))
+		}()
+	})
+
+	// runTask validates the inputs, then runs task against the database in
+	// the background with both action buttons disabled. action names the
+	// operation in the audit log and progressID is the message shown
+	// meanwhile.
+	runTask := func(action, progressID string, task func(ctx context.Context, db *sql.DB, target accountTarget, account, hash string) string) {
+		entry := generated()
+		hash := strings.TrimSpace(entry.Hash)
+		if hash == "" {
+			statusLabel.SetText(tr("db.generate_first"))
+			return
+		}
+		account := strings.TrimSpace(accountEntry.Text)
+		if account == "" {
+			statusLabel.SetText(tr("db.account_required"))
+			return
+		}
+		if err := checkAccountName(account); err != nil {
+			statusLabel.SetText(tr("error", err))
+			return
+		}
+		if err := checkDBWrite(entry.Mode, hash, entry.Username, account); err != nil {
+			statusLabel.SetText(err.Error())
+			return
+		}
+		cfg, err := connection()
+		if err != nil {
+			statusLabel.SetText(tr("error", err))
+			return
+		}
+		target := target()
+
+		updateButton.Disable()
+		createButton.Disable()
+		progress := tr(progressID, target.noun(), account, target.table())
+		statusLabel.SetText(progress)
+		setBusyTitle(w, progress)
+		go func() {
+			defer updateButton.Enable()
+			defer createButton.Enable()
+			defer setBusyTitle(w, "")
+			defer recoverPanic(showPanic(statusLabel, nil))
+
+			db, err := openDB(cfg)
+			if err != nil {
+				auditDBWrite(action, target, account, hash, err)
+				statusLabel.SetText(tr("error.database", err))
+				return
+			}
+			defer db.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+			defer cancel()
+
+			statusLabel.SetText(task(ctx, db, target, account, hash))
+		}()
+	}
+
+	updateButton = widget.NewButton(tr("db.update"), func() {
+		runTask("update", "db.updating", func(ctx context.Context, db *sql.DB, target accountTarget, account, hash string) string {
+			rows, err := updateAccountPassword(ctx, db, target, account, hash)
+			if err == nil && rows == 0 {
+				auditDBWrite("update", target, account, hash, errors.New("no such account"))
+			} else {
+				auditDBWrite("update", target, account, hash, err)
+			}
+			switch {
+			case err != nil:
+				return tr("error.database", err)
+			case rows == 0:
+				return tr("db.no_rows", target.noun(), account, target.table())
+			default:
+				return tr("db.updated", rows, target.noun(), account, target.table())
+			}
+		})
+	})
+
+	createButton = widget.NewButton(tr("db.create"), func() {
+		email := strings.TrimSpace(emailEntry.Text)
+		source := strings.TrimSpace(sourceEntry.Text)
+		runTask("create", "db.creating", func(ctx context.Context, db *sql.DB, target accountTarget, account, hash string) string {
+			id, err := createAccount(ctx, db, target, newAccount{
+				Name:   account,
+				Hash:   hash,
+				Email:  email,
+				Source: source,
+			})
+			auditDBWrite("create", target, account, hash, err)
+			switch {
+			case errors.Is(err, errAccountExists):
+				return tr("db.exists", target.noun(), account, target.table())
+			case err != nil:
+				return tr("error.database", err)
+			default:
+				return tr("db.created", target.noun(), account, target.table(), id)
+			}
+		})
+	})
+
+	return container.NewVBox(
+		widget.NewForm(
+			widget.NewFormItem(tr("db.host"), hostEntry),
+			widget.NewFormItem(tr("db.port"), portEntry),
+			widget.NewFormItem(tr("db.user"), userEntry),
+			widget.NewFormItem(tr("db.password"), passwordEntry),
+			widget.NewFormItem(tr("db.database"), nameEntry),
+		),
+		container.NewHBox(rememberCheck, layout.NewSpacer(), testButton),
+		widget.NewSeparator(),
+		widget.NewForm(
+			widget.NewFormItem(tr("db.account_name"), accountEntry),
+			widget.NewFormItem(tr("db.email"), emailEntry),
+			widget.NewFormItem(tr("db.source"), sourceEntry),
+		),
+		container.NewHBox(updateButton, createButton),
+	)
+}
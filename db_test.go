@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPingDBFails(t *testing.T) {
+	// A port with nothing listening is refused straight away.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	if err := pingDB(context.Background(), dbConfig{Host: "127.0.0.1", Port: port, User: "root", Database: "peq"}); err == nil {
+		t.Error("no error for a closed port")
+	}
+
+	// A server that accepts but never speaks MySQL must not hang the ping.
+	ln, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = pingDB(ctx, dbConfig{Host: "127.0.0.1", Port: ln.Addr().(*net.TCPAddr).Port, User: "root", Database: "peq"})
+	if err == nil {
+		t.Error("no error from a silent server")
+	}
+	if elapsed := time.Since(start); elapsed > dbPingTimeout {
+		t.Errorf("silent server took %v", elapsed)
+	}
+}
+
+func TestCheckDBWrite(t *testing.T) {
+	for _, tt := range []struct {
+		mode                        int
+		hash, generatedFor, account string
+		ok                          bool
+	}{
+		{14, goldenVectors[14], "", "anyone", true},
+		{1, goldenVectors[1], "", "anyone", true},
+		{2, goldenVectors[2], goldenUsername, goldenUsername, true},
+		// Hashed with the username of another account.
+		{2, goldenVectors[2], goldenUsername, "someoneelse", false},
+		{13, goldenVectors[13], "", "anyone", true},
+		// Edited into something that isn't a hash, or of another mode.
+		{14, "hunter2", "", "anyone", false},
+		{14, goldenVectors[1], "", "anyone", false},
+		{1, goldenVectors[5], "", "anyone", false},
+		{14, goldenVectors[14][:len(goldenVectors[14])-4], "", "anyone", false},
+	} {
+		err := checkDBWrite(tt.mode, tt.hash, tt.generatedFor, tt.account)
+		if (err == nil) != tt.ok {
+			t.Errorf("mode %d, %.20s for %q written to %q: err = %v, want ok = %v", tt.mode, tt.hash, tt.generatedFor, tt.account, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+//go:build !cli
+
+package main
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Keyboard shortcuts handled by the entries on each tab. Ctrl+Enter runs the
+// tab's main action and Ctrl+Shift+C copies the Generate output (Cmd on macOS).
+var (
+	submitShortcut       = &desktop.CustomShortcut{KeyName: fyne.KeyReturn, Modifier: fyne.KeyModifierShortcutDefault}
+	submitKeypadShortcut = &desktop.CustomShortcut{KeyName: fyne.KeyEnter, Modifier: fyne.KeyModifierShortcutDefault}
+	copyOutputShortcut   = &desktop.CustomShortcut{KeyName: fyne.KeyC, Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift}
+)
+
+// shortcutEntry is an Entry that runs its own keyboard shortcuts. Fyne hands
+// shortcuts to the focused widget instead of the canvas, so shortcuts added
+// with Canvas().AddShortcut never fire while the user is typing in a field.
+// Because each entry belongs to one tab, this also keeps the shortcuts scoped
+// to the active tab.
+type shortcutEntry struct {
+	widget.Entry
+	shortcuts map[string]func()
+}
+
+func newShortcutEntry() *shortcutEntry {
+	e := &shortcutEntry{}
+	e.ExtendBaseWidget(e)
+	return e
+}
+
+// newShortcutPasswordEntry is the shortcutEntry counterpart of
+// widget.NewPasswordEntry: masked, with the eye button Fyne adds to password
+// entries to reveal the text.
+func newShortcutPasswordEntry() *shortcutEntry {
+	e := &shortcutEntry{}
+	e.Password = true
+	e.Wrapping = fyne.TextWrap(fyne.TextTruncateClip)
+	e.ExtendBaseWidget(e)
+	return e
+}
+
+// mask hides a password the user revealed with the eye button. Entry.Refresh
+// doesn't refresh the button, so its icon is updated separately.
+func (e *shortcutEntry) mask() {
+	e.Password = true
+	e.Refresh()
+	if e.ActionItem != nil {
+		e.ActionItem.Refresh()
+	}
+}
+
+// addShortcut runs fn when shortcut is typed while the entry has focus.
+func (e *shortcutEntry) addShortcut(shortcut fyne.Shortcut, fn func()) {
+	if e.shortcuts == nil {
+		e.shortcuts = make(map[string]func())
+	}
+	e.shortcuts[shortcut.ShortcutName()] = fn
+}
+
+// addSubmitShortcut binds Ctrl+Enter on both the main and keypad Enter keys.
+func (e *shortcutEntry) addSubmitShortcut(fn func()) {
+	e.addShortcut(submitShortcut, fn)
+	e.addShortcut(submitKeypadShortcut, fn)
+}
+
+// TypedShortcut implements fyne.Shortcutable, falling back to the standard
+// Entry shortcuts (copy, paste, select all...) for anything not registered.
+func (e *shortcutEntry) TypedShortcut(shortcut fyne.Shortcut) {
+	if fn, ok := e.shortcuts[shortcut.ShortcutName()]; ok {
+		fn()
+		return
+	}
+	e.Entry.TypedShortcut(shortcut)
+}
+
+// addUsernameMenu gives entry a dropdown of previously used usernames, kept
+// in prefs (never with passwords), with an item to forget them all. The
+// returned function records a username as used.
+func addUsernameMenu(w fyne.Window, entry *shortcutEntry, prefs fyne.Preferences, statusLabel *widget.Label) func(string) {
+	var button *widget.Button
+	button = widget.NewButtonWithIcon("", theme.MenuDropDownIcon(), func() {
+		var items []*fyne.MenuItem
+		for _, name := range prefs.StringList(prefRecentUsernames) {
+			name := name
+			items = append(items, fyne.NewMenuItem(name, func() { entry.SetText(name) }))
+		}
+		if len(items) == 0 {
+			none := fyne.NewMenuItem(tr("usernames.none"), nil)
+			none.Disabled = true
+			items = append(items, none)
+		}
+		forget := fyne.NewMenuItem(tr("usernames.forget"), func() {
+			prefs.RemoveValue(prefRecentUsernames)
+			statusLabel.SetText(tr("usernames.forgotten"))
+		})
+		forget.Disabled = len(prefs.StringList(prefRecentUsernames)) == 0
+		items = append(items, fyne.NewMenuItemSeparator(), forget)
+
+		pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(button)
+		widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", items...), w.Canvas(), pos.AddXY(0, button.Size().Height))
+	})
+	entry.ActionItem = button
+
+	return func(name string) {
+		if name != "" {
+			prefs.SetStringList(prefRecentUsernames, addRecent(prefs.StringList(prefRecentUsernames), name, maxRecentUsernames))
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// explainHash hashes password in mode like eqcryptHash and writes each step
+// to w, for -explain: the parameters, the salt and, for SCrypt, the encoded
+// salt string that escrypt actually passes to scrypt instead of the raw salt
+// bytes. salt is the raw Argon2 or SCrypt salt to use, or nil for a random
+// one; the hex modes ignore it.
+func explainHash(w io.Writer, username, password string, mode int, salt []byte) error {
+	if password == "" {
+		return errEmptyPassword
+	}
+	if err := checkPasswordLength(password); err != nil {
+		return err
+	}
+	m, ok := lookupMode(mode)
+	if !ok {
+		return fmt.Errorf("unsupported encryption mode: %d", mode)
+	}
+	if m.NeedsUsername && username == "" {
+		return fmt.Errorf("mode %d requires a username", mode)
+	}
+
+	saltLen, argon2Cost := argon2Settings()
+	scryptCost := scryptSettings()
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "mode\t%d - %s\n", mode, m.Label)
+	var hash string
+	var err error
+	switch m.family() {
+	case "argon2":
+		if salt == nil {
+			if salt, err = randomSalt(saltLen); err != nil {
+				return err
+			}
+		}
+		if hash, err = hashArgon2WithParams(password, salt, argon2Cost, argon2KeyLen); err != nil {
+			return err
+		}
+		_, _, key, err := parseArgon2PHC(hash)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(tw, "algorithm\tArgon2id v=19\n")
+		fmt.Fprintf(tw, "parameters\t%v (memory in KiB, passes, lanes)\n", argon2Cost)
+		fmt.Fprintf(tw, "salt (hex)\t%x (%d bytes)\n", salt, len(salt))
+		fmt.Fprintf(tw, "salt (PHC base64)\t%s\n", base64.RawStdEncoding.EncodeToString(salt))
+		fmt.Fprintf(tw, "derived key (hex)\t%x (%d bytes)\n", key, len(key))
+
+	case "scrypt":
+		if salt == nil {
+			if salt, err = randomSalt(scryptSaltLen); err != nil {
+				return err
+			}
+		}
+		encodedSalt := encode64Bytes(salt)
+		if hash, err = hashSCryptWithParams(password, encodedSalt, scryptCost); err != nil {
+			return err
+		}
+		_, _, key, err := parseSCryptMCF(hash)
+		if err != nil {
+			return err
+		}
+		p := scryptCost
+		fmt.Fprintf(tw, "algorithm\tescrypt (libsodium crypto_pwhash_scryptsalsa208sha256_str)\n")
+		fmt.Fprintf(tw, "parameters\t%v (ln=%d)\n", p, p.LogN)
+		fmt.Fprintf(tw, "salt (hex)\t%x (%d bytes)\n", salt, len(salt))
+		fmt.Fprintf(tw, "salt (encoded)\t%s\n", encodedSalt)
+		fmt.Fprintf(tw, "scrypt salt input (hex)\t%s (the %d characters of the encoded salt, not the raw bytes)\n",
+			hex.EncodeToString([]byte(encodedSalt)), len(encodedSalt))
+		fmt.Fprintf(tw, "header\t%s%s%s%s (N as log2, r and p in escrypt base64)\n", scryptPrefix,
+			encode64Uint32(p.LogN, scryptLogNBits), encode64Uint32(p.R, scryptRPBits), encode64Uint32(p.P, scryptRPBits))
+		fmt.Fprintf(tw, "derived key (hex)\t%x (%d bytes)\n", key, len(key))
+
+	default:
+		if hash, err = eqcryptHash(username, password, mode); err != nil {
+			return err
+		}
+		f := m.family()
+		switch {
+		case tripleModes[mode]:
+			digest := tripleDigests[f]
+			fmt.Fprintf(tw, "computed as\t%s(%s(username) + %s(password)), lowercase hex digests\n", f, f, f)
+			fmt.Fprintf(tw, "username digest\t%s\n", digest(username))
+			fmt.Fprintf(tw, "password digest\t%s\n", digest(password))
+		case strings.Contains(m.Label, "(password:username)"):
+			fmt.Fprintf(tw, "computed as\t%s(password + \":\" + username)\n", f)
+		case strings.Contains(m.Label, "(username:password)"):
+			fmt.Fprintf(tw, "computed as\t%s(username + \":\" + password)\n", f)
+		default:
+			fmt.Fprintf(tw, "computed as\t%s(password), unsalted\n", f)
+		}
+	}
+	fmt.Fprintf(tw, "hash\t%s\n", outputHash(hash))
+	return tw.Flush()
+}
+
+func randomSalt(n int) ([]byte, error) {
+	salt := make([]byte, n)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
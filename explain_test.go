@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExplainHash(t *testing.T) {
+	for _, tc := range []struct {
+		mode int
+		salt []byte
+		want []string
+	}{
+		{13, goldenSalt(16), []string{"m=65536, t=2, p=1", "000102030405060708090a0b0c0d0e0f", "AAECAwQFBgcICQoLDA0ODw"}},
+		{14, goldenSalt(32), []string{"N=16384, r=8, p=1 (ln=14)", "$7$C6..../....", "not the raw bytes"}},
+		{4, nil, []string{"md5(md5(username) + md5(password))", hashMD5(goldenUsername)}},
+		{7, nil, []string{`sha1(username + ":" + password)`}},
+		{19, nil, []string{"sha3-256(password), unsalted"}},
+	} {
+		var out bytes.Buffer
+		if err := explainHash(&out, goldenUsername, goldenPassword, tc.mode, tc.salt); err != nil {
+			t.Fatalf("mode %d: %v", tc.mode, err)
+		}
+		for _, want := range append(tc.want, goldenVectors[tc.mode]) {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("mode %d: missing %q:\n%s", tc.mode, want, out.String())
+			}
+		}
+	}
+
+	// The encoded salt, as bytes, is what scrypt gets.
+	var out bytes.Buffer
+	explainHash(&out, "", goldenPassword, 14, goldenSalt(32))
+	encoded := goldenVectors[14][14:strings.LastIndex(goldenVectors[14], "$")]
+	if !strings.Contains(out.String(), encoded) {
+		t.Errorf("encoded salt %q missing:\n%s", encoded, out.String())
+	}
+
+	if err := explainHash(&out, "", goldenPassword, 2, nil); err == nil {
+		t.Error("mode 2 without a username: no error")
+	}
+	if err := explainHash(&out, "", goldenPassword, 99, nil); err == nil {
+		t.Error("mode 99: no error")
+	}
+}
+
+func TestRunCLIExplain(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"-cli", "-explain", "-mode", "14", "-password", goldenPassword}, strings.NewReader(""), &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("exit code %d, stderr: %s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	hash := strings.TrimSpace(strings.TrimPrefix(lines[len(lines)-1], "hash"))
+	if ok, err := verifySCrypt(hash, goldenPassword); !ok || err != nil {
+		t.Errorf("explained hash %q does not verify: %v", hash, err)
+	}
+	if code := runCLI([]string{"-cli", "-explain", "-json", "-password", goldenPassword}, strings.NewReader(""), &stdout, &stderr); code != exitUsage {
+		t.Errorf("-explain -json: exit code %d, want %d", code, exitUsage)
+	}
+}
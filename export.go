@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// exportAllModes hashes one username/password pair with every given mode and
+// returns a labeled plain-text table, for comparing against what a server has
+// stored. Modes that need a username are skipped when none is given.
+func exportAllModes(username, password string, modes []int, now time.Time) string {
+	var b strings.Builder
+
+	if username == "" {
+		fmt.Fprintf(&b, "EQEmu password hashes (no username)\n")
+	} else {
+		fmt.Fprintf(&b, "EQEmu password hashes for username %q\n", username)
+	}
+	fmt.Fprintf(&b, "Generated %s\n", now.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "Note: Argon2 (13) and SCrypt (14) use a random salt, so their hashes differ on every run.\n\n")
+
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Mode\tName\tHash")
+	for _, mode := range modes {
+		var result string
+		if modeNeedsUsername[mode] && username == "" {
+			result = "(skipped: username required)"
+		} else if hash, err := eqcryptHash(username, password, mode); err != nil {
+			result = fmt.Sprintf("(error: %v)", err)
+		} else {
+			result = hash
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", mode, modeName(mode), result)
+	}
+	tw.Flush()
+
+	return b.String()
+}
@@ -0,0 +1,745 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/sha3"
+	"golang.org/x/text/unicode/norm"
+)
+
+// modeInfo describes one loginserver encryption mode. modeTable is the only
+// place modes are defined; the option labels and per-mode sets below are all
+// derived from it.
+type modeInfo struct {
+	Number        int
+	Label         string
+	NeedsUsername bool
+	Weak          bool // built on MD5 or SHA1, see weakModes
+	UsesColon     bool // joins password and username with ":", see modeUsesColon
+	HashFunc      func(username, password string) (string, error)
+}
+
+// Matches EQEmu loginserver/encryption.h EncryptionMode enum, in mode order:
+// modeTable[i] is mode i+1.
+var modeTable = []modeInfo{
+	{1, "MD5", false, true, false, hashPlain(hashMD5)},
+	{2, "MD5 (password:username)", true, true, true, hashPasswordUsername(hashMD5)},
+	{3, "MD5 (username:password)", true, true, true, hashUsernamePassword(hashMD5)},
+	{4, "MD5 Triple", true, true, false, hashTriple(hashMD5)},
+	{5, "SHA1", false, true, false, hashPlain(hashSHA1)},
+	{6, "SHA1 (password:username)", true, true, true, hashPasswordUsername(hashSHA1)},
+	{7, "SHA1 (username:password)", true, true, true, hashUsernamePassword(hashSHA1)},
+	{8, "SHA1 Triple", true, true, false, hashTriple(hashSHA1)},
+	{9, "SHA512", false, false, false, hashPlain(hashSHA512)},
+	{10, "SHA512 (password:username)", true, false, true, hashPasswordUsername(hashSHA512)},
+	{11, "SHA512 (username:password)", true, false, true, hashUsernamePassword(hashSHA512)},
+	{12, "SHA512 Triple", true, false, false, hashTriple(hashSHA512)},
+	{13, "Argon2", false, false, false, func(_, password string) (string, error) { return hashArgon2(password) }},
+	{14, "SCrypt", false, false, false, func(_, password string) (string, error) { return hashSCrypt(password) }},
+	{15, "SHA256 [fork]", false, false, false, hashPlain(hashSHA256)},
+	{16, "SHA256 (password:username) [fork]", true, false, true, hashPasswordUsername(hashSHA256)},
+	{17, "SHA256 (username:password) [fork]", true, false, true, hashUsernamePassword(hashSHA256)},
+	{18, "SHA256 Triple [fork]", true, false, false, hashTriple(hashSHA256)},
+	{19, "SHA3-256 [experimental]", false, false, false, hashPlain(hashSHA3_256)},
+	{20, "SHA3-256 (password:username) [experimental]", true, false, true, hashPasswordUsername(hashSHA3_256)},
+	{21, "SHA3-256 (username:password) [experimental]", true, false, true, hashUsernamePassword(hashSHA3_256)},
+	{22, "SHA3-256 Triple [experimental]", true, false, false, hashTriple(hashSHA3_256)},
+	{23, "SHA3-512 [experimental]", false, false, false, hashPlain(hashSHA3_512)},
+	{24, "SHA3-512 (password:username) [experimental]", true, false, true, hashPasswordUsername(hashSHA3_512)},
+	{25, "SHA3-512 (username:password) [experimental]", true, false, true, hashUsernamePassword(hashSHA3_512)},
+	{26, "SHA3-512 Triple [experimental]", true, false, false, hashTriple(hashSHA3_512)},
+}
+
+// lookupMode returns the table entry for a mode number.
+func lookupMode(mode int) (modeInfo, bool) {
+	if mode < 1 || mode > len(modeTable) {
+		return modeInfo{}, false
+	}
+	return modeTable[mode-1], true
+}
+
+// family is the hash algorithm a mode is built on, e.g. "sha512" for all of
+// modes 9-12, taken from the first word of its label.
+func (m modeInfo) family() string {
+	family, _, _ := strings.Cut(m.Label, " ")
+	return strings.ToLower(family)
+}
+
+// algorithm names the kind of hash a mode produces, e.g. "SCrypt (escrypt
+// $7$)" or "SHA1 (hex)", so a status message shows the family at a glance.
+func (m modeInfo) algorithm() string {
+	switch m.family() {
+	case "argon2":
+		return "Argon2id (PHC $argon2id$)"
+	case "scrypt":
+		return "SCrypt (escrypt $7$)"
+	}
+	family, _, _ := strings.Cut(m.Label, " ")
+	return family + " (hex)"
+}
+
+// modeOptions are the mode select labels, e.g. "2 - MD5 (password:username)".
+var modeOptions = func() []string {
+	options := make([]string, len(modeTable))
+	for i, m := range modeTable {
+		options[i] = fmt.Sprintf("%d - %s", m.Number, m.Label)
+	}
+	return options
+}()
+
+// modeSet returns the set of mode numbers for which has is true.
+func modeSet(has func(modeInfo) bool) map[int]bool {
+	set := make(map[int]bool)
+	for _, m := range modeTable {
+		if has(m) {
+			set[m.Number] = true
+		}
+	}
+	return set
+}
+
+// Modes 1-14 are the stock loginserver modes. Anything after that only exists
+// in some loginserver forks and is hidden unless enabled in Settings: modes
+// 15-18 (SHA256), then the experimental SHA3 modes 19-26 that a couple of forks
+// tried, behind a separate setting.
+const (
+	standardModeCount = 14
+	forkModeCount     = 18
+)
+
+// experimental reports whether a mode is one of the experimental SHA3 modes.
+func (m modeInfo) experimental() bool {
+	return m.Number > forkModeCount
+}
+
+// visibleModeCount is how many modes the mode lists show. Lists are in mode
+// order without gaps, so showing the experimental modes shows the fork modes
+// before them too.
+func visibleModeCount(showForkModes, showExperimentalModes bool) int {
+	switch {
+	case showExperimentalModes:
+		return len(modeTable)
+	case showForkModes:
+		return forkModeCount
+	}
+	return standardModeCount
+}
+
+// The loginserver's default mode depends on whether it was built with
+// ENABLE_SECURITY (which links libsodium).
+const (
+	defaultModeWithSecurity    = 13
+	defaultModeWithoutSecurity = 6
+)
+
+func buildDefaultMode(enableSecurity bool) int {
+	if enableSecurity {
+		return defaultModeWithSecurity
+	}
+	return defaultModeWithoutSecurity
+}
+
+// visibleModeOptions returns the mode select options, with or without the
+// fork-only and experimental modes, marking the default mode for the
+// loginserver build. Options are always in mode order starting at mode 1, so
+// an option's index is its mode number minus one.
+func visibleModeOptions(showForkModes, showExperimentalModes, enableSecurity bool) []string {
+	options := make([]string, visibleModeCount(showForkModes, showExperimentalModes))
+	copy(options, modeOptions)
+	def := buildDefaultMode(enableSecurity)
+	options[def-1] += " " + tr("generate.build_default")
+	return options
+}
+
+// parseModeFromSelection returns the mode of a mode select label, e.g. 2 for
+// "2 - MD5 (password:username)". ok is false when nothing is selected yet
+// (an empty label) or the label doesn't start with a supported mode.
+func parseModeFromSelection(sel string) (mode int, ok bool) {
+	number, _, _ := strings.Cut(sel, " ")
+	mode, err := strconv.Atoi(number)
+	if err != nil {
+		return 0, false
+	}
+	if _, ok := lookupMode(mode); !ok {
+		return 0, false
+	}
+	return mode, true
+}
+
+// modeOptionIndex is the inverse of parseModeFromSelection: the index of
+// mode's option in options, or -1 if it isn't listed.
+func modeOptionIndex(options []string, mode int) int {
+	return slices.IndexFunc(options, func(option string) bool {
+		m, ok := parseModeFromSelection(option)
+		return ok && m == mode
+	})
+}
+
+// parseModeNumber parses a mode number typed instead of picked, accepting
+// only the modes listed in options.
+func parseModeNumber(text string, options []string) (int, error) {
+	mode, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || modeOptionIndex(options, mode) < 0 {
+		return 0, fmt.Errorf("mode must be a number from 1 to %d", len(options))
+	}
+	return mode, nil
+}
+
+// modeDescription explains in Markdown what a mode computes and how to select
+// it in the loginserver, for the Generate tab's mode info popup.
+func modeDescription(mode int, enableSecurity bool) string {
+	if mode < 1 || mode > len(modeOptions) {
+		return ""
+	}
+	text := fmt.Sprintf("**%s**\n\n%s\n\n%s", modeOptions[mode-1], tr(fmt.Sprintf("mode.%d", mode)), tr("mode.setting", mode))
+	if mode == buildDefaultMode(enableSecurity) {
+		text += "\n\n" + tr("mode.build_default")
+	}
+	return text
+}
+
+// modeName returns the option label for a mode without its number, e.g.
+// "MD5 (password:username)".
+func modeName(mode int) string {
+	m, ok := lookupMode(mode)
+	if !ok {
+		return fmt.Sprintf("unknown mode %d", mode)
+	}
+	return m.Label
+}
+
+// Modes that require a username
+var modeNeedsUsername = modeSet(func(m modeInfo) bool { return m.NeedsUsername })
+
+// Modes built on MD5 or SHA1, which are broken for password storage. They
+// stay available for existing databases but the GUI warns about them.
+var weakModes = modeSet(func(m modeInfo) bool { return m.Weak })
+
+// Modes that join the password and username with a ":" separator. Loginserver
+// does a plain concatenation, so a username containing ":" still produces the
+// same hash the server computes, but the input is ambiguous: "a:b" + ":" + "c"
+// and "a" + ":" + "b:c" hash identically.
+var modeUsesColon = modeSet(func(m modeInfo) bool { return m.UsesColon })
+
+// Modes that hash with a random salt, so the same password gives a different
+// hash every time, all of which verify.
+var saltedModes = modeSet(func(m modeInfo) bool { return m.family() == "argon2" || m.family() == "scrypt" })
+
+// usernameWarning returns a warning to show alongside a generated hash when
+// the username makes the mode's input ambiguous, or "" if there is none.
+func usernameWarning(mode int, username string) string {
+	if modeUsesColon[mode] && strings.Contains(username, ":") {
+		return tr("warning.username_colon", mode)
+	}
+	return ""
+}
+
+// trimInput strips surrounding whitespace from s when trim is set. The bool
+// reports whether s had any surrounding whitespace, so callers can warn about
+// it either way.
+func trimInput(s string, trim bool) (string, bool) {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == s {
+		return s, false
+	}
+	if trim {
+		return trimmed, true
+	}
+	return s, true
+}
+
+// Largest password file readPasswordFile accepts, well past any token and
+// maxPasswordLength's default.
+const maxPasswordFileSize = 64 << 10
+
+// readPasswordFile reads the whole of a file whose contents are the password,
+// such as an API token.
+func readPasswordFile(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxPasswordFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxPasswordFileSize {
+		return nil, fmt.Errorf("password file is over %d KiB", maxPasswordFileSize>>10)
+	}
+	return data, nil
+}
+
+// passwordFromFile returns a password file's bytes exactly, without the
+// trimming and normalization typed passwords get. With stripNewline, one
+// trailing "\n" or "\r\n", as editors and echo leave, is removed first.
+func passwordFromFile(data []byte, stripNewline bool) (string, error) {
+	password := string(data)
+	if stripNewline && strings.HasSuffix(password, "\n") {
+		password = strings.TrimSuffix(strings.TrimSuffix(password, "\n"), "\r")
+	}
+	if password == "" {
+		return "", errors.New("password file is empty")
+	}
+	return password, nil
+}
+
+// whitespaceNote describes what happened to a field with surrounding
+// whitespace. field is the message ID of the field's name.
+func whitespaceNote(field string, trimmed bool) string {
+	if trimmed {
+		return tr("whitespace.removed", tr(field))
+	}
+	return tr("whitespace.kept", tr(field))
+}
+
+// isASCII reports whether s is plain 7-bit ASCII.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeInput NFC-normalizes s when nfc is set. loginserver hashes the
+// bytes the client sends without any normalization, so an accented
+// character typed as one code point or as a letter plus combining mark gives
+// a different hash. The bool reports whether s has non-ASCII characters at
+// all, so callers can warn that the form matters either way.
+func normalizeInput(s string, nfc bool) (string, bool) {
+	if isASCII(s) {
+		return s, false
+	}
+	if nfc {
+		return norm.NFC.String(s), true
+	}
+	return s, true
+}
+
+// unicodeNote warns about a field with non-ASCII characters. field is the
+// message ID of the field's name.
+func unicodeNote(field string, normalized bool) string {
+	if normalized {
+		return tr("unicode.normalized", tr(field))
+	}
+	return tr("unicode.kept", tr(field))
+}
+
+// --- Hash functions matching loginserver/encryption.cpp ---
+
+func hashMD5(s string) string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(s)))
+}
+
+func hashSHA1(s string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(s)))
+}
+
+// SHA256 isn't in stock loginserver; modes 15-18 follow the same patterns as
+// the other SHA families for forks that added it.
+func hashSHA256(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
+}
+
+func hashSHA512(s string) string {
+	return fmt.Sprintf("%x", sha512.Sum512([]byte(s)))
+}
+
+// SHA3 (FIPS 202, not the original Keccak padding) isn't in any mainstream
+// loginserver; modes 19-26 follow the same four patterns for compatibility
+// testing against the forks that experimented with it.
+func hashSHA3_256(s string) string {
+	return fmt.Sprintf("%x", sha3.Sum256([]byte(s)))
+}
+
+func hashSHA3_512(s string) string {
+	return fmt.Sprintf("%x", sha3.Sum512([]byte(s)))
+}
+
+// crypto_pwhash_SALTBYTES, the salt length libsodium and loginserver use.
+const defaultArgon2SaltLen = 16
+
+// Argon2 needs at least 8 bytes of salt (RFC 9106). The upper bound only
+// keeps the PHC string a sane length.
+const (
+	argon2MinSaltLen = 8
+	argon2MaxSaltLen = 64
+)
+
+// argon2SaltLen is the salt length hashArgon2 generates. Anything but the
+// default is for interop with verifiers other than libsodium; the GUI sets
+// it from Settings and the CLI from -argon2-salt-length.
+var argon2SaltLen = defaultArgon2SaltLen
+
+// kdfSettingsMu guards argon2SaltLen, argon2Generate, scryptGenerate,
+// scryptMaxMemory and maxPasswordLength, which the Settings tab can change
+// while a hash runs on another goroutine.
+// Outside tests they are only accessed through the functions below.
+var kdfSettingsMu sync.RWMutex
+
+// argon2Settings returns the salt length and parameters new Argon2 hashes
+// are generated with.
+func argon2Settings() (saltLen int, params argon2Params) {
+	kdfSettingsMu.RLock()
+	defer kdfSettingsMu.RUnlock()
+	return argon2SaltLen, argon2Generate
+}
+
+// scryptSettings returns the parameters new SCrypt hashes are generated with.
+func scryptSettings() scryptParams {
+	kdfSettingsMu.RLock()
+	defer kdfSettingsMu.RUnlock()
+	return scryptGenerate
+}
+
+func setArgon2SaltLen(n int) {
+	kdfSettingsMu.Lock()
+	defer kdfSettingsMu.Unlock()
+	argon2SaltLen = n
+}
+
+func setGenerateParams(argon2Cost argon2Params, scryptCost scryptParams) {
+	kdfSettingsMu.Lock()
+	defer kdfSettingsMu.Unlock()
+	argon2Generate, scryptGenerate = argon2Cost, scryptCost
+}
+
+// checkArgon2SaltLen refuses salt lengths outside the supported range.
+func checkArgon2SaltLen(n int) error {
+	if n < argon2MinSaltLen || n > argon2MaxSaltLen {
+		return fmt.Errorf("Argon2 salt length must be between %d and %d bytes, not %d", argon2MinSaltLen, argon2MaxSaltLen, n)
+	}
+	return nil
+}
+
+// Argon2id matching libsodium crypto_pwhash_str with INTERACTIVE parameters.
+// Output is the standard PHC string format that libsodium produces.
+func hashArgon2(password string) (string, error) {
+	saltLen, params := argon2Settings()
+	if err := checkArgon2SaltLen(saltLen); err != nil {
+		return "", err
+	}
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return hashArgon2WithParams(password, salt, params, argon2KeyLen)
+}
+
+// crypto_pwhash_OPSLIMIT_INTERACTIVE = 2
+// crypto_pwhash_MEMLIMIT_INTERACTIVE = 67108864 bytes = 65536 KiB
+var argon2Interactive = argon2Params{Memory: 65536, Time: 2, Threads: 1}
+
+// argon2Generate are the parameters hashArgon2 uses: INTERACTIVE, like the
+// loginserver, unless config.json says otherwise.
+var argon2Generate = argon2Interactive
+
+// crypto_pwhash_STRBYTES leaves room for a 32-byte hash
+const argon2KeyLen = 32
+
+// hashArgon2WithSalt is hashArgon2 with a caller-supplied salt, so tests can
+// produce known-answer vectors.
+func hashArgon2WithSalt(password string, salt []byte) (string, error) {
+	return hashArgon2WithParams(password, salt, argon2Interactive, argon2KeyLen)
+}
+
+// hashArgon2WithParams is hashArgon2WithSalt with explicit cost parameters
+// and hash length, for reproducing an existing hash from a template.
+func hashArgon2WithParams(password string, salt []byte, params argon2Params, keyLen uint32) (string, error) {
+	if err := params.checkMinimum(); err != nil {
+		return "", err
+	}
+	hash := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, keyLen)
+
+	// PHC string format (matches libsodium output)
+	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
+	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
+
+	return fmt.Sprintf("$argon2id$v=19$m=%d,t=%d,p=%d$%s$%s",
+		params.Memory, params.Time, params.Threads, b64Salt, b64Hash), nil
+}
+
+// Custom base64 alphabet used by libsodium's escrypt (scrypt MCF format).
+const itoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// encode64Uint32 encodes a value as little-endian custom base64, matching
+// libsodium's escrypt encode64_uint32 function.
+func encode64Uint32(value uint32, bits int) string {
+	var result []byte
+	for i := 0; i < bits; i += 6 {
+		result = append(result, itoa64[value&0x3f])
+		value >>= 6
+	}
+	return string(result)
+}
+
+// encode64Bytes encodes raw bytes in the escrypt custom base64 format,
+// matching libsodium's escrypt encode64 function.
+func encode64Bytes(src []byte) string {
+	var result []byte
+	i := 0
+	for i+3 <= len(src) {
+		v := uint(src[i]) | uint(src[i+1])<<8 | uint(src[i+2])<<16
+		result = append(result, itoa64[v&0x3f])
+		result = append(result, itoa64[(v>>6)&0x3f])
+		result = append(result, itoa64[(v>>12)&0x3f])
+		result = append(result, itoa64[(v>>18)&0x3f])
+		i += 3
+	}
+	remaining := len(src) - i
+	if remaining == 1 {
+		v := uint(src[i])
+		result = append(result, itoa64[v&0x3f])
+		result = append(result, itoa64[(v>>6)&0x3f])
+	} else if remaining == 2 {
+		v := uint(src[i]) | uint(src[i+1])<<8
+		result = append(result, itoa64[v&0x3f])
+		result = append(result, itoa64[(v>>6)&0x3f])
+		result = append(result, itoa64[(v>>12)&0x3f])
+	}
+	return string(result)
+}
+
+// SCrypt matching libsodium crypto_pwhash_scryptsalsa208sha256_str with
+// INTERACTIVE parameters. Output is the escrypt $7$ MCF format.
+//
+// Key detail: escrypt passes the base64-ENCODED salt string (not the raw
+// bytes) as the salt parameter to the scrypt KDF. This matches how
+// libsodium's escrypt_r works internally.
+func hashSCrypt(password string) (string, error) {
+	rawSalt := make([]byte, scryptSaltLen)
+	if _, err := rand.Read(rawSalt); err != nil {
+		return "", err
+	}
+	return hashSCryptWithParams(password, encode64Bytes(rawSalt), scryptSettings())
+}
+
+// crypto_pwhash_scryptsalsa208sha256_OPSLIMIT_INTERACTIVE = 524288
+// crypto_pwhash_scryptsalsa208sha256_MEMLIMIT_INTERACTIVE = 16777216
+// Translates to: N=16384, r=8, p=1
+var scryptInteractive = scryptParams{LogN: 14, R: 8, P: 1}
+
+// scryptGenerate are the parameters hashSCrypt uses, see argon2Generate.
+var scryptGenerate = scryptInteractive
+
+// hashSCryptWithSalt is hashSCrypt with a caller-supplied raw salt, so tests
+// can produce known-answer vectors. The salt is encoded before use exactly as
+// hashSCrypt does.
+func hashSCryptWithSalt(password string, rawSalt []byte) (string, error) {
+	// Encode salt to custom base64 first — escrypt uses the ENCODED salt
+	// string as the PBKDF2 salt input, not the raw bytes.
+	return hashSCryptWithParams(password, encode64Bytes(rawSalt), scryptInteractive)
+}
+
+// hashSCryptWithParams hashes with an already-encoded salt and explicit cost
+// parameters. Taking the encoded salt lets a template's salt be reused
+// verbatim, even one that doesn't decode to a whole number of bytes.
+func hashSCryptWithParams(password, encodedSalt string, params scryptParams) (string, error) {
+	if err := params.checkMinimum(); err != nil {
+		return "", err
+	}
+	if err := params.checkMemory(); err != nil {
+		return "", err
+	}
+	dk, err := scrypt.Key([]byte(password), []byte(encodedSalt), int(params.N()), int(params.R), int(params.P), scryptKeyLen)
+	if err != nil {
+		return "", err
+	}
+
+	// Build escrypt MCF format: $7$<log2N><r as 30-bit><p as 30-bit><salt_b64>$<hash_b64>
+	mcf := scryptPrefix +
+		encode64Uint32(params.LogN, scryptLogNBits) +
+		encode64Uint32(params.R, scryptRPBits) +
+		encode64Uint32(params.P, scryptRPBits) +
+		encodedSalt + "$" +
+		encode64Bytes(dk)
+
+	return mcf, nil
+}
+
+// errSCryptDerive marks verifySCrypt errors from deriving the key of a
+// well-formed hash, such as parameters over the memory cap.
+var errSCryptDerive = errors.New("scrypt error")
+
+// verifySCrypt replicates libsodium's crypto_pwhash_scryptsalsa208sha256_str_verify.
+// Like verifyArgon2, the cost parameters and salt come from the stored string.
+// Its result tells apart why a hash didn't verify: an error wrapping
+// errSCryptDerive if scrypt couldn't run, any other error if the hash is
+// malformed (say a bad prefix or a missing salt delimiter, as parseSCryptMCF
+// describes), and false without an error for a password that doesn't match.
+func verifySCrypt(storedHash, password string) (bool, error) {
+	params, encodedSalt, expectedDK, err := parseSCryptMCF(storedHash)
+	if err != nil {
+		return false, err
+	}
+	if err := params.checkMemory(); err != nil {
+		return false, fmt.Errorf("%w: %v", errSCryptDerive, err)
+	}
+
+	dk, err := scrypt.Key([]byte(password), []byte(encodedSalt), int(params.N()), int(params.R), int(params.P), len(expectedDK))
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", errSCryptDerive, err)
+	}
+	return compareDerived(expectedDK, dk)
+}
+
+// errEmptyPassword is returned by eqcryptHash for an empty password. The
+// loginserver would hash one without complaint, but here it is almost always
+// a caller bug, and for the username modes the result is just a hash of the
+// username.
+var errEmptyPassword = errors.New("refusing to hash an empty password")
+
+// Default for maxPasswordLength. Far longer than any real passphrase, but
+// small enough that a paste of the wrong clipboard contents is caught.
+const defaultMaxPasswordLength = 1024
+
+// maxPasswordLength is the longest password, in characters, that will be
+// hashed; 0 means no limit. Nothing here truncates passwords, so anything
+// longer is refused with errPasswordTooLong rather than silently cut. The
+// GUI sets it from Settings and the CLI from -max-password-length, both
+// through setMaxPasswordLength since a hash may be running at the time.
+var maxPasswordLength = defaultMaxPasswordLength
+
+var errPasswordTooLong = errors.New("password too long")
+
+func setMaxPasswordLength(n int) {
+	kdfSettingsMu.Lock()
+	defer kdfSettingsMu.Unlock()
+	maxPasswordLength = n
+}
+
+// checkPasswordLength enforces maxPasswordLength.
+func checkPasswordLength(password string) error {
+	kdfSettingsMu.RLock()
+	limit := maxPasswordLength
+	kdfSettingsMu.RUnlock()
+	if n := utf8.RuneCountInString(password); limit > 0 && n > limit {
+		return fmt.Errorf("%w: %d characters, over the %d-character limit", errPasswordTooLong, n, limit)
+	}
+	return nil
+}
+
+// eqcryptHash replicates loginserver/encryption.cpp eqcrypt_hash. Like the
+// loginserver, the username and password are concatenated as-is: nothing is
+// escaped or rejected, so see usernameWarning for the colon-separated modes.
+// The exceptions are an empty password, see errEmptyPassword, and one over
+// maxPasswordLength.
+func eqcryptHash(username, password string, mode int) (string, error) {
+	if password == "" {
+		return "", errEmptyPassword
+	}
+	if err := checkPasswordLength(password); err != nil {
+		return "", err
+	}
+	m, ok := lookupMode(mode)
+	if !ok {
+		return "", fmt.Errorf("unsupported encryption mode: %d", mode)
+	}
+	hash, err := m.HashFunc(username, password)
+	if err != nil {
+		return "", err
+	}
+	if err := checkHashFormat(mode, hash); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// eqcryptHashCtx is eqcryptHash that gives up as soon as ctx is done.
+func eqcryptHashCtx(ctx context.Context, username, password string, mode int) (string, error) {
+	return withContext(ctx, func() (string, error) {
+		return eqcryptHash(username, password, mode)
+	})
+}
+
+// withContext runs hash and returns its result, or ctx.Err() if ctx is done
+// first. argon2.IDKey and scrypt.Key can't be interrupted, so a cancelled
+// derivation keeps running in the background and its result is dropped. A
+// panic in hash is returned as an errPanic error.
+func withContext(ctx context.Context, hash func() (string, error)) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	type result struct {
+		hash string
+		err  error
+	}
+	done := make(chan result, 1) // buffered so an abandoned hash can still finish
+	go func() {
+		defer recoverPanic(func(err error) { done <- result{"", err} })
+		h, err := hash()
+		done <- result{h, err}
+	}()
+	select {
+	case r := <-done:
+		return r.hash, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// errWrongOutputFormat means a mode produced output in a different format
+// than it should, e.g. an MD5 mode returning something other than 32 hex
+// digits. That can only be a bug here, so callers should show it loudly
+// rather than hand out a hash the loginserver will never match.
+var errWrongOutputFormat = errors.New("internal error: hash has the wrong format for its mode")
+
+// checkHashFormat is a sanity check that hash looks like the output of mode,
+// as judged by the same detection the Verify tab uses.
+func checkHashFormat(mode int, hash string) error {
+	if !slices.Contains(detectHashModes(hash), mode) {
+		return fmt.Errorf("%w: mode %d (%s) produced %d characters %.12q...", errWrongOutputFormat, mode, modeName(mode), len(hash), hash)
+	}
+	var err error
+	switch mode {
+	case 13:
+		_, _, _, err = parseArgon2PHC(hash)
+	case 14:
+		_, _, _, err = parseSCryptMCF(hash)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: mode %d (%s): %v", errWrongOutputFormat, mode, modeName(mode), err)
+	}
+	return nil
+}
+
+// The four ways encryption.cpp combines a digest with the username:
+// password alone, "password:username", "username:password", and the digest
+// of the two digests concatenated.
+
+func hashPlain(digest func(string) string) func(username, password string) (string, error) {
+	return func(_, password string) (string, error) {
+		return digest(password), nil
+	}
+}
+
+func hashPasswordUsername(digest func(string) string) func(username, password string) (string, error) {
+	return func(username, password string) (string, error) {
+		return digest(password + ":" + username), nil
+	}
+}
+
+func hashUsernamePassword(digest func(string) string) func(username, password string) (string, error) {
+	return func(username, password string) (string, error) {
+		return digest(username + ":" + password), nil
+	}
+}
+
+func hashTriple(digest func(string) string) func(username, password string) (string, error) {
+	return func(username, password string) (string, error) {
+		return digest(digest(username) + digest(password)), nil
+	}
+}
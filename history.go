@@ -0,0 +1,75 @@
+//go:build !cli
+
+package main
+
+import (
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Oldest history entries are dropped once the session has this many.
+const maxHistoryEntries = 50
+
+// historyEntry records one generated hash. The plaintext password is never
+// stored.
+type historyEntry struct {
+	Time     time.Time
+	Mode     int
+	Username string
+	Hash     string
+}
+
+func (h historyEntry) String() string {
+	hash := h.Hash
+	if len(hash) > 24 {
+		hash = hash[:24] + "..."
+	}
+	if h.Username == "" {
+		return tr("history.entry", h.Time.Format("15:04:05"), h.Mode, hash)
+	}
+	return tr("history.entry_user", h.Time.Format("15:04:05"), h.Mode, h.Username, hash)
+}
+
+// newHistoryPanel builds the in-memory list of hashes generated this session,
+// newest first, each with a button to copy it again. The returned function
+// records a new entry.
+func newHistoryPanel(w fyne.Window, statusLabel *widget.Label, prefs fyne.Preferences) (fyne.CanvasObject, func(historyEntry)) {
+	var entries []historyEntry
+	rows := container.NewVBox()
+	empty := widget.NewLabel(tr("history.empty"))
+
+	refresh := func() {
+		rows.RemoveAll()
+		if len(entries) == 0 {
+			rows.Add(empty)
+			return
+		}
+		for i := len(entries) - 1; i >= 0; i-- {
+			entry := entries[i]
+			copyButton := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+				copyToClipboard(w, statusLabel, prefs, entry.Hash)
+			})
+			rows.Add(container.NewBorder(nil, nil, nil, copyButton, widget.NewLabel(entry.String())))
+		}
+	}
+	refresh()
+
+	clearButton := widget.NewButton(tr("history.clear"), func() {
+		entries = nil
+		refresh()
+	})
+
+	add := func(entry historyEntry) {
+		entries = append(entries, entry)
+		if len(entries) > maxHistoryEntries {
+			entries = entries[len(entries)-maxHistoryEntries:]
+		}
+		refresh()
+	}
+
+	return container.NewVBox(rows, container.NewHBox(clearButton)), add
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+)
+
+// UI message catalogs, one JSON object of message ID -> text per language.
+//
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// language is one entry in the Settings language selector.
+type language struct {
+	Code string // catalog file name in locales/
+	Name string // shown in its own language
+}
+
+var languages = []language{
+	{Code: "en", Name: "English"},
+	{Code: "pt", Name: "Português"},
+}
+
+const defaultLanguage = "en"
+
+// fallbackMessages is the English catalog, used for any ID missing from the
+// current one. messages is the catalog for the selected language.
+var (
+	fallbackMessages = mustLoadCatalog(defaultLanguage)
+	messages         = fallbackMessages
+)
+
+func loadCatalog(code string) (map[string]string, error) {
+	data, err := localeFiles.ReadFile("locales/" + code + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("unknown language %q", code)
+	}
+	var catalog map[string]string
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("locales/%s.json: %v", code, err)
+	}
+	return catalog, nil
+}
+
+func mustLoadCatalog(code string) map[string]string {
+	catalog, err := loadCatalog(code)
+	if err != nil {
+		panic(err)
+	}
+	return catalog
+}
+
+// setLanguage switches the catalog tr uses. Widgets already built keep their
+// old text, so the GUI rebuilds its content afterwards.
+func setLanguage(code string) error {
+	catalog, err := loadCatalog(code)
+	if err != nil {
+		return err
+	}
+	messages = catalog
+	return nil
+}
+
+// tr returns the message for id in the current language, formatted with args
+// as by fmt.Sprintf. Messages missing from the current catalog fall back to
+// English, and an unknown ID is returned as-is so it's easy to spot.
+func tr(id string, args ...any) string {
+	msg, ok := messages[id]
+	if !ok {
+		if msg, ok = fallbackMessages[id]; !ok {
+			msg = id
+		}
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+var formatVerb = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z]`)
+
+// Every translation must use the same format verbs, in the same order, as
+// the English message it replaces, or tr would garble its arguments.
+func TestCatalogsMatchEnglish(t *testing.T) {
+	for _, lang := range languages {
+		catalog, err := loadCatalog(lang.Code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for id, msg := range catalog {
+			english, ok := fallbackMessages[id]
+			if !ok {
+				t.Errorf("%s: %q is not in the English catalog", lang.Code, id)
+				continue
+			}
+			if got, want := formatVerb.FindAllString(msg, -1), formatVerb.FindAllString(english, -1); !slices.Equal(got, want) {
+				t.Errorf("%s: %q uses verbs %v, English uses %v", lang.Code, id, got, want)
+			}
+		}
+	}
+}
+
+func TestTrFallback(t *testing.T) {
+	defer func() { messages = fallbackMessages }()
+
+	messages = map[string]string{"verify.button": "Verificar"}
+	if got := tr("verify.button"); got != "Verificar" {
+		t.Errorf("translated message = %q", got)
+	}
+	if got := tr("generate.generated", 14, "SCrypt", 101); got != "Mode 14 SCrypt hash generated (101 chars)" {
+		t.Errorf("missing message did not fall back to English: %q", got)
+	}
+	if got := tr("no.such.id"); got != "no.such.id" {
+		t.Errorf("unknown ID = %q, want the ID itself", got)
+	}
+	if err := setLanguage("xx"); err == nil {
+		t.Error("expected an error for an unknown language")
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var errNoModeInConfig = errors.New("no encryption mode found in loginserver config")
+
+// loginserverConfig is the subset of the EQEmu loginserver's login.json that
+// we care about. The encryption mode normally lives under "security", but a
+// bare top-level "mode" is accepted as well.
+type loginserverConfig struct {
+	Security struct {
+		Mode *int `json:"mode"`
+	} `json:"security"`
+	Mode *int `json:"mode"`
+}
+
+// loginserverMode reads the encryption mode the loginserver is configured to
+// use from its JSON config.
+func loginserverMode(r io.Reader) (int, error) {
+	var cfg loginserverConfig
+	if err := json.NewDecoder(r).Decode(&cfg); err != nil {
+		return 0, fmt.Errorf("parsing loginserver config: %w", err)
+	}
+
+	mode := cfg.Security.Mode
+	if mode == nil {
+		mode = cfg.Mode
+	}
+	if mode == nil {
+		return 0, errNoModeInConfig
+	}
+	if _, ok := lookupMode(*mode); !ok {
+		return 0, fmt.Errorf("unsupported encryption mode in loginserver config: %d", *mode)
+	}
+	return *mode, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoginserverMode(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   int
+		err    bool
+	}{
+		{"security section", `{"security": {"mode": 13, "allow_token_login": true}}`, 13, false},
+		{"top level", `{"mode": 6}`, 6, false},
+		{"security wins", `{"mode": 6, "security": {"mode": 14}}`, 14, false},
+		{"missing", `{"security": {"allow_password_login": true}}`, 0, true},
+		{"out of range", `{"security": {"mode": 42}}`, 0, true},
+		{"not json", `[security]\nmode = 14`, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := loginserverMode(strings.NewReader(tt.config))
+		if (err != nil) != tt.err {
+			t.Errorf("%s: err = %v, want error: %v", tt.name, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got mode %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := loginserverMode(strings.NewReader(`{}`)); !errors.Is(err, errNoModeInConfig) {
+		t.Errorf("empty config: got %v, want errNoModeInConfig", err)
+	}
+}
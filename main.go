@@ -1,396 +1,2158 @@
+//go:build !cli
+
 package main
 
 import (
-	"crypto/md5"
-	"crypto/rand"
-	"crypto/sha1"
-	"crypto/sha512"
-	"encoding/base64"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
-	"golang.org/x/crypto/argon2"
-	"golang.org/x/crypto/scrypt"
+	"github.com/skip2/go-qrcode"
 )
 
-// Matches EQEmu loginserver/encryption.h EncryptionMode enum
-var modeOptions = []string{
-	"1 - MD5",
-	"2 - MD5 (password:username)",
-	"3 - MD5 (username:password)",
-	"4 - MD5 Triple",
-	"5 - SHA1",
-	"6 - SHA1 (password:username) [default without ENABLE_SECURITY]",
-	"7 - SHA1 (username:password)",
-	"8 - SHA1 Triple",
-	"9 - SHA512",
-	"10 - SHA512 (password:username)",
-	"11 - SHA512 (username:password)",
-	"12 - SHA512 Triple",
-	"13 - Argon2 [default with ENABLE_SECURITY]",
-	"14 - SCrypt",
-}
+// Preferences keys used to restore state between runs
+const (
+	prefMode         = "mode"
+	prefOutputFormat = "outputFormat"
+	prefWindowWidth  = "windowWidth"
+	prefWindowHeight = "windowHeight"
 
-// Modes that require a username
-var modeNeedsUsername = map[int]bool{
-	2: true, 3: true, 4: true,
-	6: true, 7: true, 8: true,
-	10: true, 11: true, 12: true,
-}
+	prefClipboardClearSeconds = "clipboardClearSeconds"
+	prefLanguage              = "language"
+	prefAuditLog              = "auditLog"
+	prefAuditLogPath          = "auditLogPath"
+	prefSCryptMaxMemoryMiB    = "scryptMaxMemoryMiB"
+	prefMaxPasswordLength     = "maxPasswordLength"
+	prefArgon2SaltLength      = "argon2SaltLength"
+	prefTheme                 = "theme"
+	prefTrimUsername          = "trimUsername"
+	prefTrimPassword          = "trimPassword"
+	prefNormalizeUnicode      = "normalizeUnicode"
+	prefShowSalt              = "showSalt"
+	prefAutoCopy              = "autoCopy"
+	prefUppercaseHex          = "uppercaseHex"
+	prefRecentUsernames       = "recentUsernames"
+	prefShowForkModes         = "showForkModes"
+	prefShowExperimentalModes = "showExperimentalModes"
+	prefEnableSecurity        = "enableSecurity"
+	prefEnvVarName            = "envVarName"
+
+	prefPasswordFileStripNewline = "passwordFileStripNewline"
+)
+
+// Choices for the Settings tab theme selector
+const (
+	themeSystem = "System"
+	themeLight  = "Light"
+	themeDark   = "Dark"
+)
 
-// --- Hash functions matching loginserver/encryption.cpp ---
+// Copied hashes are wiped from the clipboard after this many seconds unless
+// overridden in the Settings tab. Zero disables clearing.
+const defaultClipboardClearSeconds = 30
 
-func hashMD5(s string) string {
-	return fmt.Sprintf("%x", md5.Sum([]byte(s)))
+// clipboardClearer wipes copied text from the clipboard after a delay, unless
+// the clipboard has been overwritten with something else in the meantime.
+type clipboardClearer struct {
+	mu    sync.Mutex
+	timer *time.Timer
 }
 
-func hashSHA1(s string) string {
-	return fmt.Sprintf("%x", sha1.Sum([]byte(s)))
+var clipboardClear clipboardClearer
+
+// schedule arranges for text to be cleared from cb after the given delay,
+// replacing any clear that is still pending from an earlier copy.
+func (c *clipboardClearer) schedule(cb fyne.Clipboard, text string, after time.Duration, onCleared func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if after <= 0 {
+		return
+	}
+	c.timer = time.AfterFunc(after, func() {
+		if content, err := clipboardContent(cb); err != nil || content != text {
+			return // user copied something else, leave it alone
+		}
+		if setClipboardContent(cb, "") == nil {
+			onCleared()
+		}
+	})
 }
 
-func hashSHA512(s string) string {
-	return fmt.Sprintf("%x", sha512.Sum512([]byte(s)))
+// showPanic returns a recoverPanic report that shows the panic in
+// statusLabel, after running cleanup (e.g. re-enabling buttons) if given.
+func showPanic(statusLabel *widget.Label, cleanup func()) func(error) {
+	return func(err error) {
+		if cleanup != nil {
+			cleanup()
+		}
+		statusLabel.SetText(tr("error.panic", err))
+	}
 }
 
-// Argon2id matching libsodium crypto_pwhash_str with INTERACTIVE parameters.
-// Output is the standard PHC string format that libsodium produces.
-func hashArgon2(password string) (string, error) {
-	salt := make([]byte, 16) // crypto_pwhash_SALTBYTES
-	if _, err := rand.Read(salt); err != nil {
-		return "", err
+// guard wraps a handler so a panic in it is shown in statusLabel instead of
+// closing the window.
+func guard(statusLabel *widget.Label, handler func()) func() {
+	return func() {
+		defer recoverPanic(showPanic(statusLabel, nil))
+		handler()
 	}
+}
 
-	// crypto_pwhash_OPSLIMIT_INTERACTIVE = 2
-	// crypto_pwhash_MEMLIMIT_INTERACTIVE = 67108864 bytes = 65536 KiB
-	timeCost := uint32(2)
-	memoryCost := uint32(65536) // KiB
-	threads := uint8(1)
-	keyLen := uint32(32)
+// copyToClipboard copies text and schedules it to be cleared again after the
+// configured timeout.
+func copyToClipboard(w fyne.Window, statusLabel *widget.Label, prefs fyne.Preferences, text string) {
+	statusLabel.SetText(copyAndScheduleClear(w, statusLabel, prefs, text))
+}
 
-	hash := argon2.IDKey([]byte(password), salt, timeCost, memoryCost, threads, keyLen)
+// copyAndScheduleClear is copyToClipboard returning the status message
+// instead of showing it, for callers that add it to their own.
+func copyAndScheduleClear(w fyne.Window, statusLabel *widget.Label, prefs fyne.Preferences, text string) string {
+	cb := w.Clipboard()
+	if err := writeClipboard(cb, text); err != nil {
+		return tr("clipboard.unavailable")
+	}
 
-	// PHC string format (matches libsodium output)
-	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
-	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
+	seconds := prefs.IntWithFallback(prefClipboardClearSeconds, defaultClipboardClearSeconds)
+	if seconds <= 0 {
+		return tr("clipboard.copied", len(text))
+	}
 
-	return fmt.Sprintf("$argon2id$v=19$m=%d,t=%d,p=%d$%s$%s",
-		memoryCost, timeCost, threads, b64Salt, b64Hash), nil
+	clipboardClear.schedule(cb, text, time.Duration(seconds)*time.Second, func() {
+		statusLabel.SetText(tr("clipboard.cleared"))
+	})
+	return tr("clipboard.copied_clears", len(text), seconds)
 }
 
-// Custom base64 alphabet used by libsodium's escrypt (scrypt MCF format).
-const itoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
-
-// encode64Uint32 encodes a value as little-endian custom base64, matching
-// libsodium's escrypt encode64_uint32 function.
-func encode64Uint32(value uint32, bits int) string {
-	var result []byte
-	for i := 0; i < bits; i += 6 {
-		result = append(result, itoa64[value&0x3f])
-		value >>= 6
+// newStrengthMeter creates the segmented bar and label shown under the
+// Generate tab's password field.
+func newStrengthMeter() (*fyne.Container, *widget.Label) {
+	bar := container.NewGridWithColumns(len(strengthLabels))
+	for range strengthLabels {
+		segment := canvas.NewRectangle(theme.Color(theme.ColorNameDisabled))
+		segment.SetMinSize(fyne.NewSize(0, 6))
+		bar.Add(segment)
 	}
-	return string(result)
+	return bar, widget.NewLabel("")
 }
 
-// encode64Bytes encodes raw bytes in the escrypt custom base64 format,
-// matching libsodium's escrypt encode64 function.
-func encode64Bytes(src []byte) string {
-	var result []byte
-	i := 0
-	for i+3 <= len(src) {
-		v := uint(src[i]) | uint(src[i+1])<<8 | uint(src[i+2])<<16
-		result = append(result, itoa64[v&0x3f])
-		result = append(result, itoa64[(v>>6)&0x3f])
-		result = append(result, itoa64[(v>>12)&0x3f])
-		result = append(result, itoa64[(v>>18)&0x3f])
-		i += 3
-	}
-	remaining := len(src) - i
-	if remaining == 1 {
-		v := uint(src[i])
-		result = append(result, itoa64[v&0x3f])
-		result = append(result, itoa64[(v>>6)&0x3f])
-	} else if remaining == 2 {
-		v := uint(src[i]) | uint(src[i+1])<<8
-		result = append(result, itoa64[v&0x3f])
-		result = append(result, itoa64[(v>>6)&0x3f])
-		result = append(result, itoa64[(v>>12)&0x3f])
-	}
-	return string(result)
+// updateStrengthMeter fills one segment per strength level, colored from red
+// (weak) to green (strong).
+func updateStrengthMeter(bar *fyne.Container, label *widget.Label, password string) {
+	score := scorePassword(password)
+
+	fill := theme.Color(theme.ColorNameSuccess)
+	switch {
+	case score <= 1:
+		fill = theme.Color(theme.ColorNameError)
+	case score == 2:
+		fill = theme.Color(theme.ColorNameWarning)
+	}
+
+	for i, obj := range bar.Objects {
+		segment := obj.(*canvas.Rectangle)
+		if password != "" && i <= score {
+			segment.FillColor = fill
+		} else {
+			segment.FillColor = theme.Color(theme.ColorNameDisabled)
+		}
+		segment.Refresh()
+	}
+
+	if password == "" {
+		label.SetText("")
+	} else {
+		label.SetText(tr("strength.label", tr(strengthLabels[score])))
+	}
 }
 
-// SCrypt matching libsodium crypto_pwhash_scryptsalsa208sha256_str with
-// INTERACTIVE parameters. Output is the escrypt $7$ MCF format.
-//
-// Key detail: escrypt passes the base64-ENCODED salt string (not the raw
-// bytes) as the salt parameter to the scrypt KDF. This matches how
-// libsodium's escrypt_r works internally.
-func hashSCrypt(password string) (string, error) {
-	rawSalt := make([]byte, 32)
-	if _, err := rand.Read(rawSalt); err != nil {
-		return "", err
-	}
-
-	// crypto_pwhash_scryptsalsa208sha256_OPSLIMIT_INTERACTIVE = 524288
-	// crypto_pwhash_scryptsalsa208sha256_MEMLIMIT_INTERACTIVE = 16777216
-	// Translates to: N=16384, r=8, p=1
-	N := 16384
-	r := 8
-	p := 1
-	keyLen := 32
-
-	// Encode salt to custom base64 first — escrypt uses the ENCODED salt
-	// string as the PBKDF2 salt input, not the raw bytes.
-	encodedSalt := encode64Bytes(rawSalt)
-
-	dk, err := scrypt.Key([]byte(password), []byte(encodedSalt), N, r, p, keyLen)
-	if err != nil {
-		return "", err
-	}
-
-	// Build escrypt MCF format: $7$<log2N><r as 30-bit><p as 30-bit><salt_b64>$<hash_b64>
-	log2N := uint32(14) // log2(16384)
-
-	mcf := "$7$" +
-		encode64Uint32(log2N, 6) +
-		encode64Uint32(uint32(r), 30) +
-		encode64Uint32(uint32(p), 30) +
-		encodedSalt + "$" +
-		encode64Bytes(dk)
-
-	return mcf, nil
+// uiGeneration counts how many times the window content has been built.
+// Fyne can't remove a preferences listener, so one added by a tab that a
+// language change has since replaced is left registered; addPrefsListener
+// makes it do nothing instead.
+var uiGeneration atomic.Int64
+
+// addPrefsListener registers listener for preference changes until the
+// window content is next rebuilt. Like any preferences listener it runs on a
+// goroutine of its own.
+func addPrefsListener(prefs fyne.Preferences, listener func()) {
+	generation := uiGeneration.Load()
+	prefs.AddChangeListener(func() {
+		if uiGeneration.Load() == generation {
+			listener()
+		}
+	})
 }
 
-// verifySCrypt replicates libsodium's crypto_pwhash_scryptsalsa208sha256_str_verify
-func verifySCrypt(storedHash, password string) bool {
-	if len(storedHash) < 14 || storedHash[:3] != "$7$" {
-		return false
+func buildGenerateTab(w fyne.Window, statusLabel *widget.Label, prefs fyne.Preferences, selectedMode binding.Int) *container.TabItem {
+	usernameEntry := newShortcutEntry()
+	usernameEntry.SetPlaceHolder(tr("generate.username_placeholder"))
+	rememberUsername := addUsernameMenu(w, usernameEntry, prefs, statusLabel)
+
+	passwordEntry := newShortcutPasswordEntry()
+	passwordEntry.SetPlaceHolder(tr("generate.password_placeholder"))
+
+	strengthBar, strengthLabel := newStrengthMeter()
+	passwordEntry.OnChanged = func(password string) {
+		updateStrengthMeter(strengthBar, strengthLabel, password)
 	}
-	lastDollar := strings.LastIndex(storedHash, "$")
-	if lastDollar <= 3 {
-		return false
+
+	showForkModes := prefs.Bool(prefShowForkModes)
+	showExperimentalModes := prefs.Bool(prefShowExperimentalModes)
+	enableSecurity := prefs.BoolWithFallback(prefEnableSecurity, true)
+	modeSelect := widget.NewSelect(visibleModeOptions(showForkModes, showExperimentalModes, enableSecurity), nil)
+	// The preference listener below runs on a goroutine of its own, so the
+	// three settings above and the rebuilding of the options are guarded.
+	var modeOptionsMu sync.Mutex
+
+	// refreshModeOptions rebuilds the options after a setting changes,
+	// keeping the selected mode if it is still listed.
+	refreshModeOptions := func() {
+		modeOptionsMu.Lock()
+		defer modeOptionsMu.Unlock()
+		mode, ok := parseModeFromSelection(modeSelect.Selected)
+		modeSelect.Options = visibleModeOptions(showForkModes, showExperimentalModes, enableSecurity)
+		if !ok || mode > len(modeSelect.Options) {
+			mode = buildDefaultMode(enableSecurity)
+		}
+		modeSelect.SetSelectedIndex(mode - 1)
+		modeSelect.Refresh()
 	}
-	encodedSalt := storedHash[14:lastDollar]
-	expectedDK := storedHash[lastDollar+1:]
 
-	dk, err := scrypt.Key([]byte(password), []byte(encodedSalt), 16384, 8, 1, 32)
-	if err != nil {
-		return false
+	usernameNote := widget.NewLabel(tr("generate.username_unused"))
+	usernameNote.TextStyle = fyne.TextStyle{Italic: true}
+
+	// The username is disabled, and ignored, unless the mode hashes it or
+	// the output format needs it as the account name.
+	var formatSelect, targetSelect *widget.Select
+	updateUsernameField := func() {
+		mode, _ := parseModeFromSelection(modeSelect.Selected)
+		switch {
+		case modeNeedsUsername[mode]:
+			usernameEntry.Enable()
+			usernameNote.SetText(tr("generate.username_required"))
+		case formatSelect != nil && outputFormat(formatSelect.SelectedIndex()).usesAccountName():
+			usernameEntry.Enable()
+			usernameNote.SetText(tr("generate.username_account_only"))
+		default:
+			usernameEntry.Disable()
+			usernameNote.SetText(tr("generate.username_unused"))
+		}
 	}
-	return encode64Bytes(dk) == expectedDK
-}
 
-// eqcryptHash replicates loginserver/encryption.cpp eqcrypt_hash
-func eqcryptHash(username, password string, mode int) (string, error) {
-	switch mode {
-	case 1:
-		return hashMD5(password), nil
-	case 2:
-		return hashMD5(password + ":" + username), nil
-	case 3:
-		return hashMD5(username + ":" + password), nil
-	case 4:
-		return hashMD5(hashMD5(username) + hashMD5(password)), nil
-	case 5:
-		return hashSHA1(password), nil
-	case 6:
-		return hashSHA1(password + ":" + username), nil
-	case 7:
-		return hashSHA1(username + ":" + password), nil
-	case 8:
-		return hashSHA1(hashSHA1(username) + hashSHA1(password)), nil
-	case 9:
-		return hashSHA512(password), nil
-	case 10:
-		return hashSHA512(password + ":" + username), nil
-	case 11:
-		return hashSHA512(username + ":" + password), nil
-	case 12:
-		return hashSHA512(hashSHA512(username) + hashSHA512(password)), nil
-	case 13:
-		return hashArgon2(password)
-	case 14:
-		return hashSCrypt(password)
-	default:
-		return "", fmt.Errorf("unsupported encryption mode: %d", mode)
+	weakModeLabel := widget.NewLabel(tr("generate.weak_mode"))
+	weakModeLabel.Importance = widget.WarningImportance
+	weakModeLabel.Wrapping = fyne.TextWrapWord
+	weakModeLabel.Hide()
+
+	// For following instructions like "use mode 11": typing a number picks
+	// the matching option, and picking an option updates the number.
+	modeNumberEntry := widget.NewEntry()
+	modeNumberEntry.SetPlaceHolder("#")
+	modeNumberEntry.OnChanged = func(text string) {
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		mode, err := parseModeNumber(text, modeSelect.Options)
+		if err != nil {
+			statusLabel.SetText(tr("generate.mode_number_invalid", len(modeSelect.Options)))
+			return
+		}
+		if current, _ := parseModeFromSelection(modeSelect.Selected); mode != current {
+			modeSelect.SetSelectedIndex(modeOptionIndex(modeSelect.Options, mode))
+		}
 	}
-}
 
-func parseModeFromSelection(sel string) int {
-	parts := strings.SplitN(sel, " ", 2)
-	if len(parts) == 0 {
-		return 0
+	modeSelect.OnChanged = func(sel string) {
+		mode, ok := parseModeFromSelection(sel)
+		if !ok {
+			return
+		}
+		if number, err := parseModeNumber(modeNumberEntry.Text, modeSelect.Options); err != nil || number != mode {
+			modeNumberEntry.SetText(strconv.Itoa(mode))
+		}
+		if weakModes[mode] {
+			weakModeLabel.Show()
+		} else {
+			weakModeLabel.Hide()
+		}
+		updateUsernameField()
+		prefs.SetInt(prefMode, mode)
+		selectedMode.Set(mode)
+	}
+	// A mode picked on the Modes tab.
+	selectedMode.AddListener(binding.NewDataListener(func() {
+		mode, _ := selectedMode.Get()
+		current, _ := parseModeFromSelection(modeSelect.Selected)
+		if i := modeOptionIndex(modeSelect.Options, mode); i >= 0 && mode != current {
+			modeSelect.SetSelectedIndex(i)
+		}
+	}))
+
+	// Restore the last used mode, or the selected build's default.
+	index := prefs.IntWithFallback(prefMode, buildDefaultMode(enableSecurity)) - 1
+	if index < 0 || index >= len(modeSelect.Options) {
+		index = buildDefaultMode(enableSecurity) - 1
 	}
-	mode, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return 0
+	modeSelect.SetSelectedIndex(index)
+
+	// Add or remove the fork-only and experimental modes when either
+	// setting changes.
+	addPrefsListener(prefs, func() {
+		fork, experimental := prefs.Bool(prefShowForkModes), prefs.Bool(prefShowExperimentalModes)
+		modeOptionsMu.Lock()
+		changed := fork != showForkModes || experimental != showExperimentalModes
+		showForkModes, showExperimentalModes = fork, experimental
+		modeOptionsMu.Unlock()
+		if changed {
+			refreshModeOptions()
+		}
+	})
+
+	// Which loginserver build the hash is for; switching it selects that
+	// build's default mode.
+	securityCheck := widget.NewCheck(tr("generate.enable_security"), func(on bool) {
+		modeOptionsMu.Lock()
+		enableSecurity = on
+		modeOptionsMu.Unlock()
+		prefs.SetBool(prefEnableSecurity, on)
+		refreshModeOptions()
+		modeSelect.SetSelectedIndex(buildDefaultMode(on) - 1)
+	})
+	securityCheck.Checked = enableSecurity
+
+	// The info button pops up what the selected mode computes.
+	var modeInfoButton *widget.Button
+	modeInfoButton = widget.NewButtonWithIcon("", theme.InfoIcon(), func() {
+		mode, _ := parseModeFromSelection(modeSelect.Selected)
+		modeOptionsMu.Lock()
+		security := enableSecurity
+		modeOptionsMu.Unlock()
+		text := widget.NewRichTextFromMarkdown(modeDescription(mode, security))
+		text.Wrapping = fyne.TextWrapWord
+		popup := widget.NewPopUp(container.NewPadded(text), w.Canvas())
+		popup.Resize(fyne.NewSize(420, 220))
+		pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(modeInfoButton)
+		popup.ShowAtPosition(pos.Add(fyne.NewPos(0, modeInfoButton.Size().Height)))
+	})
+
+	loadConfigButton := widget.NewButton(tr("generate.load_config"), func() {
+		open := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				statusLabel.SetText(tr("error", err))
+				return
+			}
+			if reader == nil {
+				return // cancelled
+			}
+			defer reader.Close()
+
+			mode, err := loginserverMode(reader)
+			if err != nil {
+				modeOptionsMu.Lock()
+				def := buildDefaultMode(enableSecurity)
+				modeOptionsMu.Unlock()
+				modeSelect.SetSelectedIndex(def - 1)
+				statusLabel.SetText(tr("generate.config_default", err, def))
+				return
+			}
+			// Extend the options right away: the preference listener
+			// runs later, and the mode can't be selected until it's listed.
+			modeOptionsMu.Lock()
+			showForkModes = showForkModes || mode > standardModeCount
+			showExperimentalModes = showExperimentalModes || mode > forkModeCount
+			fork, experimental := showForkModes, showExperimentalModes
+			modeOptionsMu.Unlock()
+			refreshModeOptions()
+			prefs.SetBool(prefShowForkModes, fork)
+			prefs.SetBool(prefShowExperimentalModes, experimental)
+			i := modeOptionIndex(modeSelect.Options, mode)
+			if i < 0 {
+				statusLabel.SetText(tr("generate.mode_number_invalid", len(modeSelect.Options)))
+				return
+			}
+			modeSelect.SetSelectedIndex(i)
+			statusLabel.SetText(tr("generate.config_selected", mode, reader.URI().Name()))
+		}, w)
+		open.SetFilter(storage.NewExtensionFileFilter([]string{".json"}))
+		open.Show()
+	})
+
+	outputEntry := newShortcutEntry()
+	outputEntry.SetPlaceHolder(tr("generate.output_placeholder"))
+
+	// The last generated result, kept raw so the output can be re-rendered
+	// when the format or target changes. The hash goroutine sets it, so it
+	// is only accessed through lastResult and setLastResult.
+	var lastMu sync.Mutex
+	var last historyEntry
+	lastResult := func() historyEntry {
+		lastMu.Lock()
+		defer lastMu.Unlock()
+		return last
 	}
-	return mode
-}
+	setLastResult := func(entry historyEntry) {
+		lastMu.Lock()
+		defer lastMu.Unlock()
+		last = entry
+	}
+	selectedTarget := func() accountTarget {
+		return accountTarget(targetSelect.SelectedIndex())
+	}
+	renderOutput := func() {
+		entry := lastResult()
+		if entry.Hash == "" || formatSelect == nil || targetSelect == nil {
+			return
+		}
+		outputEntry.SetText(formatOutput(entry.Mode, selectedTarget(), entry.Username, entry.Hash,
+			outputFormat(formatSelect.SelectedIndex())))
+	}
+
+	// The salt of the last Argon2/SCrypt hash, shown only when enabled in
+	// Settings, and a note that it's random so nobody expects the same hash
+	// twice.
+	saltLabel := widget.NewLabel("")
+	saltLabel.Wrapping = fyne.TextWrapBreak
+	saltLabel.Hide()
+	randomSaltNote := widget.NewLabel(tr("generate.random_salt"))
+	randomSaltNote.TextStyle = fyne.TextStyle{Italic: true}
+	randomSaltNote.Wrapping = fyne.TextWrapWord
+	randomSaltNote.Hide()
+	updateSalt := func() {
+		entry := lastResult()
+		if entry.Hash != "" && saltedModes[entry.Mode] {
+			randomSaltNote.Show()
+		} else {
+			randomSaltNote.Hide()
+		}
+		salt := describeSalt(entry.Hash)
+		if salt == "" || !prefs.Bool(prefShowSalt) {
+			saltLabel.Hide()
+			return
+		}
+		saltLabel.SetText(salt)
+		saltLabel.Show()
+	}
+	addPrefsListener(prefs, updateSalt)
+	// The Database panel writes what is in the output field when it holds a
+	// bare hash, and the raw result otherwise. Either way it comes with the
+	// mode and username it was generated with, which the panel checks it
+	// against before writing. Hex goes in lowercase as the loginserver
+	// writes it, even when shown in uppercase.
+	rawHash := func() historyEntry {
+		entry := lastResult()
+		if entry.Hash != "" && outputFormat(formatSelect.SelectedIndex()) == formatHash {
+			entry.Hash = strings.TrimSpace(outputEntry.Text)
+			if isHex(entry.Hash) {
+				entry.Hash = strings.ToLower(entry.Hash)
+			}
+		}
+		return entry
+	}
+	// Show the output in the new case when Uppercase hex is toggled.
+	shownUppercase := prefs.Bool(prefUppercaseHex)
+	addPrefsListener(prefs, func() {
+		if upper := prefs.Bool(prefUppercaseHex); upper != shownUppercase {
+			shownUppercase = upper
+			renderOutput()
+		}
+	})
 
-func buildGenerateTab(w fyne.Window, statusLabel *widget.Label) *container.TabItem {
-	usernameEntry := widget.NewEntry()
-	usernameEntry.SetPlaceHolder("Username (required for some modes)")
+	// The variable name for the environment variable format, shown only
+	// with that format.
+	envNameEntry := widget.NewEntry()
+	envNameEntry.SetText(prefs.StringWithFallback(prefEnvVarName, defaultEnvVarName))
+	if checkEnvVarName(envNameEntry.Text) == nil {
+		setEnvVarName(envNameEntry.Text)
+	}
+	envNameEntry.OnChanged = func(text string) {
+		name := strings.TrimSpace(text)
+		if err := checkEnvVarName(name); err != nil {
+			statusLabel.SetText(tr("error", err))
+			return
+		}
+		setEnvVarName(name)
+		prefs.SetString(prefEnvVarName, name)
+		renderOutput()
+	}
+	envNameRow := container.NewBorder(nil, nil, widget.NewLabel(tr("generate.env_name")), nil, envNameEntry)
 
-	passwordEntry := widget.NewPasswordEntry()
-	passwordEntry.SetPlaceHolder("Password")
+	formatSelect = widget.NewSelect(outputFormatOptions(), func(string) {
+		prefs.SetInt(prefOutputFormat, formatSelect.SelectedIndex())
+		if outputFormat(formatSelect.SelectedIndex()) == formatEnv {
+			envNameRow.Show()
+		} else {
+			envNameRow.Hide()
+		}
+		updateUsernameField()
+		renderOutput()
+	})
+	savedFormat := prefs.IntWithFallback(prefOutputFormat, int(formatHash))
+	if savedFormat < 0 || savedFormat >= len(formatSelect.Options) {
+		savedFormat = int(formatHash)
+	}
+	formatSelect.SetSelectedIndex(savedFormat)
 
-	modeSelect := widget.NewSelect(modeOptions, nil)
-	modeSelect.SetSelectedIndex(13) // Default: mode 14 - SCrypt
+	// Which table the hash is for. The hashing is identical; this only
+	// changes the labels, the SQL formats and where the Database panel
+	// writes.
+	outputLabel := widget.NewLabel("")
+	targetSelect = widget.NewSelect(accountTargetOptions(), func(string) {
+		outputLabel.SetText(tr("generate.output_label", selectedTarget().table()))
+		renderOutput()
+	})
+	targetSelect.SetSelectedIndex(int(targetAccount))
 
-	usernameNote := widget.NewLabel("Username is not used for this mode")
-	usernameNote.TextStyle = fyne.TextStyle{Italic: true}
+	historyPanel, addHistory := newHistoryPanel(w, statusLabel, prefs)
 
-	modeSelect.OnChanged = func(sel string) {
-		mode := parseModeFromSelection(sel)
-		if modeNeedsUsername[mode] {
-			usernameNote.SetText("Username is required for this mode")
+	// Shown while a hash is being derived so slow KDF parameters don't look
+	// like a hung window.
+	progress := widget.NewProgressBarInfinite()
+	progress.Stop()
+	progress.Hide()
+
+	// Generating, benchmarking and checking a hash are exclusive: setBusy disables both
+	// buttons while either runs and enables Cancel, which abandons the
+	// running derivation.
+	var hashButton, benchmarkButton, checkButton, cancelButton *widget.Button
+	// cancelHash cancels the running derivation. It is set when one starts
+	// and read by Cancel, so it is guarded like last.
+	var cancelMu sync.Mutex
+	var cancelHash context.CancelFunc
+	setCancelHash := func(cancel context.CancelFunc) {
+		cancelMu.Lock()
+		defer cancelMu.Unlock()
+		cancelHash = cancel
+	}
+	setBusy := func(busy bool) {
+		if busy {
+			hashButton.Disable()
+			benchmarkButton.Disable()
+			checkButton.Disable()
+			cancelButton.Enable()
+			progress.Show()
+			progress.Start()
 		} else {
-			usernameNote.SetText("Username is not used for this mode")
+			progress.Stop()
+			progress.Hide()
+			hashButton.Enable()
+			benchmarkButton.Enable()
+			checkButton.Enable()
+			cancelButton.Disable()
+		}
+	}
+	cancelButton = widget.NewButton(tr("dialog.cancel"), func() {
+		cancelMu.Lock()
+		cancel := cancelHash
+		cancelMu.Unlock()
+		if cancel != nil {
+			cancel()
+		}
+	})
+	cancelButton.Disable()
+
+	// An optional Argon2/SCrypt hash whose salt and parameters are reused
+	// for the next hash, to reproduce server output byte-for-byte.
+	var template *hashTemplate
+	templateInfo := widget.NewLabel(tr("generate.template_help"))
+	templateInfo.Wrapping = fyne.TextWrapWord
+	templateEntry := widget.NewEntry()
+	templateEntry.SetPlaceHolder("$argon2id$... or $7$...")
+	templateEntry.OnChanged = func(text string) {
+		text = strings.TrimSpace(text)
+		template = nil
+		if text == "" {
+			templateInfo.SetText(tr("generate.template_help"))
+			return
+		}
+		t, err := parseHashTemplate(text)
+		if err != nil {
+			templateInfo.SetText(tr("generate.template_invalid", err))
+			return
 		}
+		template = &t
+		info := t.String()
+		if note := t.costNote(); note != "" {
+			info += "\n" + note
+		}
+		templateInfo.SetText(info)
+		modeSelect.SetSelectedIndex(t.Mode - 1)
+	}
+
+	// Optional libsodium OPSLIMIT/MEMLIMIT pair that Argon2 and SCrypt
+	// parameters are derived from instead of the loginserver's defaults,
+	// for matching accounts created by a PHP or Python site using libsodium.
+	opsLimitEntry := widget.NewEntry()
+	opsLimitEntry.SetPlaceHolder("OPSLIMIT")
+	memLimitEntry := widget.NewEntry()
+	memLimitEntry.SetPlaceHolder("MEMLIMIT")
+	sodiumInfo := widget.NewLabel(tr("generate.sodium_help"))
+	sodiumInfo.Wrapping = fyne.TextWrapWord
+	// sodiumLimitsFromEntries returns ok false when both entries are blank.
+	sodiumLimitsFromEntries := func() (limits sodiumLimits, ok bool, err error) {
+		ops, mem := strings.TrimSpace(opsLimitEntry.Text), strings.TrimSpace(memLimitEntry.Text)
+		if ops == "" && mem == "" {
+			return limits, false, nil
+		}
+		if limits.OpsLimit, err = strconv.ParseUint(ops, 10, 64); err != nil {
+			return limits, true, fmt.Errorf("OPSLIMIT: %w", err)
+		}
+		if limits.MemLimit, err = strconv.ParseUint(mem, 10, 64); err != nil {
+			return limits, true, fmt.Errorf("MEMLIMIT: %w", err)
+		}
+		return limits, true, nil
+	}
+	updateSodiumInfo := func(string) {
+		mode, _ := parseModeFromSelection(modeSelect.Selected)
+		limits, ok, err := sodiumLimitsFromEntries()
+		switch {
+		case !ok:
+			sodiumInfo.SetText(tr("generate.sodium_help"))
+		case err != nil:
+			sodiumInfo.SetText(tr("generate.sodium_invalid", err))
+		case mode != 13 && mode != 14:
+			sodiumInfo.SetText(tr("generate.sodium_kdf_only"))
+		default:
+			params, err := describeSodiumLimits(mode, limits)
+			if err != nil {
+				sodiumInfo.SetText(tr("generate.sodium_invalid", err))
+				return
+			}
+			if note := sodiumCostNote(mode, limits); note != "" {
+				params += "\n" + note
+			}
+			sodiumInfo.SetText(params)
+		}
+	}
+	opsLimitEntry.OnChanged = updateSodiumInfo
+	memLimitEntry.OnChanged = updateSodiumInfo
+	sodiumButtons := container.NewHBox()
+	for _, name := range sodiumPresetNames {
+		name := name
+		sodiumButtons.Add(widget.NewButton(name, func() {
+			mode, _ := parseModeFromSelection(modeSelect.Selected)
+			if mode != 13 && mode != 14 {
+				statusLabel.SetText(tr("generate.sodium_kdf_only"))
+				return
+			}
+			limits, err := sodiumPreset(mode, name)
+			if err != nil {
+				statusLabel.SetText(tr("generate.sodium_invalid", err))
+				return
+			}
+			opsLimitEntry.SetText(strconv.FormatUint(limits.OpsLimit, 10))
+			memLimitEntry.SetText(strconv.FormatUint(limits.MemLimit, 10))
+		}))
+	}
+	sodiumButtons.Add(widget.NewButton(tr("generate.sodium_clear"), func() {
+		opsLimitEntry.SetText("")
+		memLimitEntry.SetText("")
+	}))
+	sodiumPanel := container.NewVBox(
+		container.NewGridWithColumns(2, opsLimitEntry, memLimitEntry),
+		sodiumButtons,
+		sodiumInfo,
+	)
+
+	// A password file replaces the entry for token-style credentials. Its
+	// bytes are hashed exactly: only the newline toggle applies, not the
+	// trim and Unicode settings.
+	var passwordFile []byte
+	passwordFileLabel := widget.NewLabel("")
+	passwordFileLabel.Wrapping = fyne.TextWrapWord
+	stripNewlineCheck := widget.NewCheck(tr("generate.password_file_strip"), func(on bool) {
+		prefs.SetBool(prefPasswordFileStripNewline, on)
+	})
+	stripNewlineCheck.SetChecked(prefs.BoolWithFallback(prefPasswordFileStripNewline, true))
+	var passwordFileRow *fyne.Container
+	clearPasswordFile := func() {
+		passwordFile = nil
+		passwordEntry.Enable()
+		passwordFileRow.Hide()
+	}
+	passwordFileRow = container.NewBorder(nil, nil, nil,
+		container.NewHBox(stripNewlineCheck, widget.NewButton(tr("generate.password_file_clear"), clearPasswordFile)),
+		passwordFileLabel)
+	passwordFileRow.Hide()
+	passwordFileButton := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				statusLabel.SetText(tr("error", err))
+				return
+			}
+			if reader == nil {
+				return // cancelled
+			}
+			defer reader.Close()
+			data, err := readPasswordFile(reader)
+			if err != nil {
+				statusLabel.SetText(tr("error", err))
+				return
+			}
+			passwordFile = data
+			passwordEntry.SetText("")
+			passwordEntry.mask()
+			passwordEntry.Disable()
+			passwordFileLabel.SetText(tr("generate.password_file_loaded", reader.URI().Name(), len(data)))
+			passwordFileRow.Show()
+		}, w)
+	})
+
+	// inputPassword returns the password to hash and any warnings about it:
+	// the loaded file's bytes, or the entry's text trimmed and normalized as
+	// set in Settings.
+	inputPassword := func() (string, []string, error) {
+		if passwordFile != nil {
+			password, err := passwordFromFile(passwordFile, stripNewlineCheck.Checked)
+			return password, nil, err
+		}
+		var warnings []string
+		trimPassword := prefs.Bool(prefTrimPassword)
+		password, spaced := trimInput(passwordEntry.Text, trimPassword)
+		if spaced {
+			warnings = append(warnings, whitespaceNote("field.password", trimPassword))
+		}
+		nfc := prefs.Bool(prefNormalizeUnicode)
+		password, nonASCII := normalizeInput(password, nfc)
+		if nonASCII {
+			warnings = append(warnings, unicodeNote("field.password", nfc))
+		}
+		return password, warnings, nil
+	}
+
+	// inputUsername returns the username to hash, trimmed and normalized as
+	// set in Settings, and whether either changed it. It is empty while the
+	// entry is disabled.
+	inputUsername := func() (username string, spaced, nonASCII bool) {
+		if usernameEntry.Disabled() {
+			return "", false, false
+		}
+		username, spaced = trimInput(usernameEntry.Text, prefs.BoolWithFallback(prefTrimUsername, true))
+		username, nonASCII = normalizeInput(username, prefs.Bool(prefNormalizeUnicode))
+		return username, spaced, nonASCII
 	}
 
-	outputEntry := widget.NewEntry()
-	outputEntry.SetPlaceHolder("Hash will appear here")
+	generate := func() {
+		if hashButton.Disabled() {
+			return
+		}
 
-	hashButton := widget.NewButton("Generate Hash", func() {
-		mode := parseModeFromSelection(modeSelect.Selected)
-		if mode == 0 {
-			statusLabel.SetText("Please select an encryption mode")
+		mode, ok := parseModeFromSelection(modeSelect.Selected)
+		if !ok {
+			statusLabel.SetText(tr("generate.select_mode"))
 			return
 		}
 
-		password := passwordEntry.Text
+		password, warnings, err := inputPassword()
+		if err != nil {
+			statusLabel.SetText(tr("error", err))
+			return
+		}
 		if password == "" {
-			statusLabel.SetText("Password is required")
+			statusLabel.SetText(tr("generate.password_required"))
 			return
 		}
 
-		username := usernameEntry.Text
+		nfc := prefs.Bool(prefNormalizeUnicode)
+		trimUsername := prefs.BoolWithFallback(prefTrimUsername, true)
+		username, spaced, nonASCII := inputUsername()
+		if err := checkAccountName(username); err != nil {
+			statusLabel.SetText(tr("error", err))
+			return
+		}
 		if modeNeedsUsername[mode] && username == "" {
-			statusLabel.SetText("Username is required for this mode")
+			statusLabel.SetText(tr("generate.username_required"))
 			return
 		}
+		if modeNeedsUsername[mode] {
+			if spaced {
+				warnings = append(warnings, whitespaceNote("field.username", trimUsername))
+			}
+			if nonASCII {
+				warnings = append(warnings, unicodeNote("field.username", nfc))
+			}
+			if warning := usernameWarning(mode, username); warning != "" {
+				warnings = append(warnings, warning)
+			}
+		}
+
+		hashFunc := func() (string, error) {
+			return eqcryptHash(username, password, mode)
+		}
+		if template != nil {
+			if t := *template; t.Mode == mode {
+				hashFunc = func() (string, error) { return t.hash(password) }
+				warnings = append(warnings, tr("generate.template_used"))
+				if note := t.costNote(); note != "" {
+					warnings = append(warnings, note)
+				}
+			} else {
+				warnings = append(warnings, tr("generate.template_ignored", t.Mode))
+			}
+		}
+		// A matching template already fixes the parameters, so the libsodium
+		// limits only apply without one.
+		if (mode == 13 || mode == 14) && (template == nil || template.Mode != mode) {
+			limits, ok, err := sodiumLimitsFromEntries()
+			if err != nil {
+				statusLabel.SetText(tr("generate.sodium_invalid", err))
+				return
+			}
+			if ok {
+				hashFunc = func() (string, error) { return hashWithSodiumLimits(mode, password, limits) }
+				warnings = append(warnings, tr("generate.sodium_used"))
+				if note := sodiumCostNote(mode, limits); note != "" {
+					warnings = append(warnings, note)
+				}
+			}
+		}
+
+		// Argon2 and SCrypt take long enough to freeze the window, so hash
+		// off the UI goroutine and keep the button disabled until done.
+		ctx, cancel := context.WithCancel(context.Background())
+		setCancelHash(cancel)
+		setBusy(true)
+		statusLabel.SetText(tr("generate.generating", mode))
+
+		go func() {
+			defer cancel()
+			defer recoverPanic(showPanic(statusLabel, func() { setBusy(false) }))
+			hash, err := withContext(ctx, hashFunc)
+			setBusy(false)
+
+			if errors.Is(err, context.Canceled) {
+				statusLabel.SetText(tr("generate.cancelled"))
+				return
+			}
+			if err != nil {
+				statusLabel.SetText(tr("error", err))
+				if errors.Is(err, errWrongOutputFormat) {
+					dialog.ShowError(err, w)
+				}
+				setLastResult(historyEntry{})
+				outputEntry.SetText("")
+				updateSalt()
+				return
+			}
+
+			entry := historyEntry{Time: time.Now(), Mode: mode, Username: username, Hash: hash}
+			setLastResult(entry)
+			renderOutput()
+			updateSalt()
+			info, _ := lookupMode(mode)
+			status := tr("generate.generated", mode, info.algorithm(), len(hash))
+			if len(warnings) > 0 {
+				status += " - " + strings.Join(warnings, "; ")
+			}
+			if prefs.Bool(prefAutoCopy) {
+				status += " - " + copyAndScheduleClear(w, statusLabel, prefs, outputEntry.Text)
+			}
+			statusLabel.SetText(status)
+			auditGenerate(mode, username, hash)
+			if modeNeedsUsername[mode] {
+				rememberUsername(username)
+			}
+			addHistory(entry)
+		}()
+	}
+	generate = guard(statusLabel, generate)
+	hashButton = widget.NewButton(tr("generate.button"), generate)
+	hashButton.Importance = widget.HighImportance
 
-		hash, err := eqcryptHash(username, password, mode)
+	// Checks the current mode, username and password against a hash pasted
+	// into a dialog, as the Verify tab would with the mode selected, without
+	// re-entering them there.
+	checkButton = widget.NewButton(tr("generate.check"), func() {
+		mode, ok := parseModeFromSelection(modeSelect.Selected)
+		if !ok {
+			statusLabel.SetText(tr("generate.select_mode"))
+			return
+		}
+		password, _, err := inputPassword()
 		if err != nil {
-			statusLabel.SetText(fmt.Sprintf("Error: %v", err))
-			outputEntry.SetText("")
+			statusLabel.SetText(tr("error", err))
+			return
+		}
+		if password == "" {
+			statusLabel.SetText(tr("generate.password_required"))
+			return
+		}
+		username, _, _ := inputUsername()
+		if modeNeedsUsername[mode] && username == "" {
+			statusLabel.SetText(tr("generate.username_required"))
 			return
 		}
 
-		outputEntry.SetText(hash)
-		statusLabel.SetText(fmt.Sprintf("Mode %d hash generated (%d chars)", mode, len(hash)))
+		hashEntry := widget.NewEntry()
+		hashEntry.SetPlaceHolder(tr("verify.hash_placeholder"))
+		// Likely what was just copied from the database.
+		if text, err := readClipboard(w.Clipboard()); err == nil && len(detectHashModes(strings.TrimSpace(text))) > 0 {
+			hashEntry.SetText(strings.TrimSpace(text))
+		}
+		form := dialog.NewForm(tr("generate.check_title", mode), tr("verify.button"), tr("dialog.cancel"),
+			[]*widget.FormItem{widget.NewFormItem(tr("generate.check_hash"), hashEntry)},
+			func(ok bool) {
+				if !ok {
+					return
+				}
+				hash, _ := cleanHash(hashEntry.Text)
+				if hash == "" {
+					statusLabel.SetText(tr("verify.both_required"))
+					return
+				}
+				ctx, cancel := context.WithCancel(context.Background())
+				setCancelHash(cancel)
+				setBusy(true)
+				statusLabel.SetText(tr("generate.checking", mode))
+				go func() {
+					defer cancel()
+					defer recoverPanic(showPanic(statusLabel, func() { setBusy(false) }))
+					verdict, err := withContext(ctx, func() (string, error) {
+						return verifyVerdict(hash, username, password, mode)
+					})
+					setBusy(false)
+					if errors.Is(err, context.Canceled) {
+						statusLabel.SetText(tr("generate.cancelled"))
+						return
+					}
+					if err != nil {
+						statusLabel.SetText(tr("error", err))
+						return
+					}
+					statusLabel.SetText(verdict)
+				}()
+			}, w)
+		form.Resize(fyne.NewSize(480, form.MinSize().Height))
+		form.Show()
 	})
-	hashButton.Importance = widget.HighImportance
 
-	copyButton := widget.NewButton("Copy to Clipboard", func() {
+	copyOutput := func() {
 		text := strings.TrimSpace(outputEntry.Text)
-		if text != "" {
-			w.Clipboard().SetContent(text)
-			statusLabel.SetText(fmt.Sprintf("Copied to clipboard! (%d chars)", len(text)))
+		if text == "" {
+			statusLabel.SetText(tr("generate.nothing_to_copy"))
+			return
+		}
+		copyToClipboard(w, statusLabel, prefs, text)
+	}
+	copyButton := widget.NewButton(tr("generate.copy"), copyOutput)
+	// Copies the output with a line saying what it is, for handing a hash
+	// to another admin.
+	copyCommentButton := widget.NewButton(tr("generate.copy_comment"), func() {
+		entry := lastResult()
+		if entry.Hash == "" {
+			statusLabel.SetText(tr("generate.nothing_to_copy"))
+			return
+		}
+		copyToClipboard(w, statusLabel, prefs, commentedOutput(entry.Mode, selectedTarget(), entry.Username, entry.Hash,
+			outputFormat(formatSelect.SelectedIndex()), entry.Time))
+	})
+
+	// Optional QR code of the output, for scanning the hash into another
+	// device. It follows the output while shown.
+	qrImage := canvas.NewImageFromImage(nil)
+	qrImage.FillMode = canvas.ImageFillContain
+	qrImage.ScaleMode = canvas.ImageScalePixels
+	qrImage.SetMinSize(fyne.NewSize(qrCodeSize, qrCodeSize))
+	qrImage.Hide()
+	updateQR := func() {
+		if qrImage.Hidden {
+			return
+		}
+		text := strings.TrimSpace(outputEntry.Text)
+		if text == "" {
+			qrImage.Image = nil
+			qrImage.Refresh()
+			return
+		}
+		code, err := qrcode.New(text, qrcode.Medium)
+		if err != nil {
+			statusLabel.SetText(tr("generate.qr_error", err))
+			return
+		}
+		qrImage.Image = code.Image(qrCodeSize)
+		qrImage.Refresh()
+	}
+	outputEntry.OnChanged = func(string) { updateQR() }
+
+	var qrButton *widget.Button
+	qrButton = widget.NewButton(tr("generate.show_qr"), func() {
+		if qrImage.Hidden {
+			qrImage.Show()
+			qrButton.SetText(tr("generate.hide_qr"))
+			updateQR()
+		} else {
+			qrImage.Hide()
+			qrButton.SetText(tr("generate.show_qr"))
 		}
 	})
 
+	// Go strings are immutable and may have been copied by the widgets, so
+	// the old password can't be overwritten in place; clearing the entries
+	// drops our references so it can be collected.
+	resetButton := widget.NewButton(tr("reset.button"), func() {
+		usernameEntry.SetText("")
+		passwordEntry.SetText("")
+		passwordEntry.mask()
+		clearPasswordFile()
+		setLastResult(historyEntry{})
+		outputEntry.SetText("")
+		updateSalt()
+		templateEntry.SetText("")
+		opsLimitEntry.SetText("")
+		memLimitEntry.SetText("")
+		statusLabel.SetText(tr("reset.done"))
+	})
+
+	// Accounts queued with Add to batch, written out together as one .sql
+	// file for onboarding many players without a database connection.
+	var provisioned []provisionEntry
+	provisionLabel := widget.NewLabel(tr("provision.empty"))
+	provisionLabel.Wrapping = fyne.TextWrapWord
+	updateProvisionLabel := func() {
+		if len(provisioned) == 0 {
+			provisionLabel.SetText(tr("provision.empty"))
+			return
+		}
+		names := make([]string, len(provisioned))
+		for i, e := range provisioned {
+			names[i] = e.Username
+		}
+		provisionLabel.SetText(tr("provision.count", len(provisioned), strings.Join(names, ", ")))
+	}
+	addProvisionButton := widget.NewButton(tr("provision.add"), func() {
+		entry := lastResult()
+		if entry.Hash == "" {
+			statusLabel.SetText(tr("provision.nothing"))
+			return
+		}
+		if entry.Username == "" {
+			statusLabel.SetText(tr("provision.username_required"))
+			return
+		}
+		var replaced bool
+		provisioned, replaced = addProvision(provisioned, provisionEntry{
+			Mode: entry.Mode, Target: selectedTarget(), Username: entry.Username, Hash: entry.Hash,
+		})
+		updateProvisionLabel()
+		if replaced {
+			statusLabel.SetText(tr("provision.replaced", entry.Username))
+		} else {
+			statusLabel.SetText(tr("provision.added", entry.Username, len(provisioned)))
+		}
+	})
+	exportProvisionButton := widget.NewButton(tr("provision.export"), func() {
+		if len(provisioned) == 0 {
+			statusLabel.SetText(tr("provision.empty"))
+			return
+		}
+		save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				statusLabel.SetText(tr("error", err))
+				return
+			}
+			if writer == nil {
+				return // cancelled
+			}
+			script := provisionSQL(provisioned, outputFormat(formatSelect.SelectedIndex()), time.Now())
+			_, err = io.WriteString(writer, script)
+			if closeErr := writer.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				statusLabel.SetText(tr("error", err))
+				return
+			}
+			statusLabel.SetText(tr("provision.exported", len(provisioned), writer.URI().Name()))
+		}, w)
+		save.SetFileName("accounts.sql")
+		save.SetFilter(storage.NewExtensionFileFilter([]string{".sql"}))
+		save.Show()
+	})
+	clearProvisionButton := widget.NewButton(tr("provision.clear"), func() {
+		provisioned = nil
+		updateProvisionLabel()
+	})
+	provisionHint := widget.NewLabelWithStyle(tr("provision.hint"), fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+	provisionHint.Wrapping = fyne.TextWrapWord
+	provisionPanel := container.NewVBox(
+		provisionHint,
+		container.NewHBox(addProvisionButton, exportProvisionButton, layout.NewSpacer(), clearProvisionButton),
+		provisionLabel,
+	)
+
+	var exportButton *widget.Button
+	exportButton = widget.NewButton(tr("generate.export_all"), func() {
+		password, _, err := inputPassword()
+		if err != nil {
+			statusLabel.SetText(tr("error", err))
+			return
+		}
+		if password == "" {
+			statusLabel.SetText(tr("generate.password_required"))
+			return
+		}
+		nfc := prefs.Bool(prefNormalizeUnicode)
+		username, _ := trimInput(usernameEntry.Text, prefs.BoolWithFallback(prefTrimUsername, true))
+		username, _ = normalizeInput(username, nfc)
+
+		modes := make([]int, len(modeSelect.Options))
+		for i := range modes {
+			modes[i] = modeTable[i].Number
+		}
+
+		exportButton.Disable()
+		statusLabel.SetText(tr("generate.exporting", len(modes)))
+		go func() {
+			defer recoverPanic(showPanic(statusLabel, exportButton.Enable))
+			table := exportAllModes(username, password, modes, time.Now())
+			exportButton.Enable()
+			copyToClipboard(w, statusLabel, prefs, table)
+		}()
+	})
+
+	// Time the selected KDF so admins can tune its cost to their host.
+	benchmarkButton = widget.NewButton(tr("generate.benchmark"), func() {
+		mode, _ := parseModeFromSelection(modeSelect.Selected)
+		if mode != 13 && mode != 14 {
+			statusLabel.SetText(tr("generate.benchmark_kdf_only"))
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		setCancelHash(cancel)
+		setBusy(true)
+		statusLabel.SetText(tr("generate.benchmarking", mode, benchmarkIterations))
+		go func() {
+			defer cancel()
+			defer recoverPanic(showPanic(statusLabel, func() { setBusy(false) }))
+			perHash, err := benchmarkMode(ctx, mode, benchmarkIterations)
+			setBusy(false)
+			if errors.Is(err, context.Canceled) {
+				statusLabel.SetText(tr("generate.cancelled"))
+				return
+			}
+			if err != nil {
+				statusLabel.SetText(tr("generate.benchmark_failed", err))
+				return
+			}
+			statusLabel.SetText(tr("generate.benchmark_result",
+				modeName(mode), perHash.Milliseconds(), benchmarkIterations, benchmarkAdvice(perHash)))
+		}()
+	})
+
+	passwordEntry.addSubmitShortcut(generate)
+	// Enter moves from username to password, then generates.
+	usernameEntry.OnSubmitted = func(string) { w.Canvas().Focus(passwordEntry) }
+	passwordEntry.OnSubmitted = func(string) { generate() }
+	for _, entry := range []*shortcutEntry{usernameEntry, passwordEntry, outputEntry} {
+		entry.addShortcut(copyOutputShortcut, copyOutput)
+	}
+
+	// Tab follows the order widgets are added, so the mode row's extra
+	// controls sit above the select to keep Tab going mode -> username ->
+	// password.
 	content := container.NewVBox(
-		widget.NewLabel("Encryption Mode:"),
-		modeSelect,
-		widget.NewLabel("Username:"),
+		container.NewHBox(widget.NewLabel(tr("generate.mode_label")), layout.NewSpacer(),
+			securityCheck, modeInfoButton, loadConfigButton),
+		container.NewBorder(nil, nil, nil,
+			container.NewGridWrap(fyne.NewSize(64, modeSelect.MinSize().Height), modeNumberEntry), modeSelect),
+		weakModeLabel,
+		widget.NewLabel(tr("generate.username_label")),
 		usernameEntry,
 		usernameNote,
-		widget.NewLabel("Password:"),
-		passwordEntry,
+		widget.NewLabel(tr("generate.password_label")),
+		container.NewBorder(nil, nil, nil, passwordFileButton, passwordEntry),
+		passwordFileRow,
+		strengthBar,
+		strengthLabel,
 		layout.NewSpacer(),
-		hashButton,
+		container.NewBorder(nil, nil, nil, container.NewHBox(checkButton, cancelButton, resetButton), hashButton),
+		progress,
 		widget.NewSeparator(),
-		widget.NewLabel("Hash Output (for login_accounts.account_password):"),
+		widget.NewForm(
+			widget.NewFormItem(tr("generate.target"), targetSelect),
+			widget.NewFormItem(tr("generate.format"), formatSelect),
+		),
+		envNameRow,
+		outputLabel,
 		outputEntry,
-		container.NewHBox(copyButton, layout.NewSpacer()),
+		randomSaltNote,
+		saltLabel,
+		container.NewHBox(copyButton, copyCommentButton, qrButton, layout.NewSpacer(), benchmarkButton, exportButton),
+		qrImage,
+		widget.NewAccordion(
+			widget.NewAccordionItem(tr("generate.history"), historyPanel),
+			widget.NewAccordionItem(tr("generate.template"), container.NewVBox(templateEntry, templateInfo)),
+			widget.NewAccordionItem(tr("generate.sodium"), sodiumPanel),
+			widget.NewAccordionItem(tr("provision.title"), provisionPanel),
+			widget.NewAccordionItem(tr("generate.database"), buildDatabasePanel(w, statusLabel, prefs, rawHash, selectedTarget)),
+			widget.NewAccordionItem(tr("api.title"), buildAPIPanel(w, statusLabel, prefs, lastResult)),
+		),
 	)
 
-	return container.NewTabItem("Generate", content)
+	return container.NewTabItem(tr("generate.tab"), container.NewVScroll(content))
+}
+
+// describeHash summarizes the format and cost parameters of a stored hash for
+// the Verify tab's details area.
+func describeHash(hash string) string {
+	switch {
+	case hash == "":
+		return ""
+	case strings.HasPrefix(hash, "$7$"):
+		params, _, _, err := parseSCryptMCF(hash)
+		if err != nil {
+			return tr("describe.scrypt_error", err)
+		}
+		return tr("describe.scrypt", params)
+	case strings.HasPrefix(hash, "$argon2"):
+		params, salt, key, err := parseArgon2PHC(hash)
+		if err != nil {
+			return tr("describe.argon2_error", err)
+		}
+		return tr("describe.argon2", params, len(salt), len(key))
+	case !isHex(hash):
+		return tr("verify.not_hex")
+	default:
+		return tr("describe.hex", len(hash))
+	}
 }
 
-func buildVerifyTab(w fyne.Window, statusLabel *widget.Label) *container.TabItem {
-	hashEntry := widget.NewEntry()
-	hashEntry.SetPlaceHolder("Paste hash from database here")
+// Width and height of the Generate tab's QR code, in pixels.
+const qrCodeSize = 256
+
+// Largest dropped file the Verify tab will read, in bytes.
+const maxDroppedFileSize = 1 << 20
+
+// buildVerifyTab builds the Verify tab. A hash file dropped anywhere on the
+// window is loaded into it, and show is called to bring the tab forward.
+func buildVerifyTab(w fyne.Window, statusLabel *widget.Label, prefs fyne.Preferences, show func()) *container.TabItem {
+	hashEntry := newShortcutEntry()
+	hashEntry.SetPlaceHolder(tr("verify.hash_placeholder"))
 
-	passwordEntry := widget.NewPasswordEntry()
-	passwordEntry.SetPlaceHolder("Password to verify")
+	passwordEntry := newShortcutPasswordEntry()
+	passwordEntry.SetPlaceHolder(tr("verify.password_placeholder"))
+
+	// The mode the hash is believed to be in. If the password fails there
+	// but matches under another mode that fits, the result says so. Like
+	// the Generate tab, it only lists the fork and experimental modes when
+	// they are shown.
+	verifyModeOptions := func() []string {
+		return append([]string{tr("verify.mode_auto")}, visibleModeOptions(prefs.Bool(prefShowForkModes),
+			prefs.Bool(prefShowExperimentalModes), prefs.BoolWithFallback(prefEnableSecurity, true))...)
+	}
+	modeSelect := widget.NewSelect(verifyModeOptions(), nil)
+	modeSelect.SetSelectedIndex(0)
+	// The listener runs on a goroutine of its own.
+	var modeOptionsMu sync.Mutex
+	addPrefsListener(prefs, func() {
+		modeOptionsMu.Lock()
+		defer modeOptionsMu.Unlock()
+		options := verifyModeOptions()
+		if slices.Equal(options, modeSelect.Options) {
+			return
+		}
+		mode, _ := parseModeFromSelection(modeSelect.Selected)
+		modeSelect.Options = options
+		// Back to auto-detect if the selected mode is no longer listed.
+		modeSelect.SetSelectedIndex(max(modeOptionIndex(options, mode), 0))
+		modeSelect.Refresh()
+	})
+	usernameEntry := newShortcutEntry()
+	usernameEntry.SetPlaceHolder(tr("verify.username_placeholder"))
+	rememberUsername := addUsernameMenu(w, usernameEntry, prefs, statusLabel)
 
 	resultLabel := widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+	// Where a failed hex verification differs from the closest mode.
+	diffText := widget.NewRichText()
+	diffText.Wrapping = fyne.TextWrapBreak
+	setDiff := func(segments []widget.RichTextSegment) {
+		diffText.Segments = segments
+		diffText.Refresh()
+	}
+
+	// Read-only breakdown of the pasted hash's parameters.
+	detailsLabel := widget.NewLabel("")
+	detailsLabel.Wrapping = fyne.TextWrapWord
+	// Copy buttons for the decoded parts of an Argon2 or SCrypt hash, for
+	// comparing salts and digests with another tool's separately.
+	componentButtons := container.NewGridWithColumns(2)
+	hashEntry.OnChanged = func(text string) {
+		hash := strings.TrimSpace(text)
+		detailsLabel.SetText(describeHash(hash))
+		componentButtons.RemoveAll()
+		components, _ := hashComponents(hash)
+		for _, c := range components {
+			value := c.Value
+			componentButtons.Add(widget.NewButtonWithIcon(c.Label, theme.ContentCopyIcon(), func() {
+				copyToClipboard(w, statusLabel, prefs, value)
+			}))
+		}
+	}
 
-	verifyButton := widget.NewButton("Verify", func() {
-		hash := strings.TrimSpace(hashEntry.Text)
-		password := passwordEntry.Text
+	// Checking runs off the UI goroutine, since a costly SCrypt or Argon2
+	// hash can take seconds, with the button disabled until it's done; Enter
+	// in the entries is ignored meanwhile, so only one check is ever in
+	// flight and the result is always the last one started.
+	var verifyButton *widget.Button
+	verify := func() {
+		if verifyButton.Disabled() {
+			return
+		}
+		hash, junk := cleanHash(hashEntry.Text)
+		trimPassword := prefs.Bool(prefTrimPassword)
+		password, spaced := trimInput(passwordEntry.Text, trimPassword)
+		setDiff(nil)
 
 		if hash == "" || password == "" {
-			statusLabel.SetText("Both hash and password are required")
+			statusLabel.SetText(tr("verify.both_required"))
 			return
 		}
 
-		statusLabel.SetText(fmt.Sprintf("Hash length: %d chars", len(hash)))
+		status := tr("verify.hash_length", len(hash))
+		if junk > 0 {
+			status += " - " + tr("verify.hash_cleaned", junk)
+		}
+		if spaced {
+			status += " - " + whitespaceNote("field.password", trimPassword)
+		}
+		nfc := prefs.Bool(prefNormalizeUnicode)
+		password, nonASCII := normalizeInput(password, nfc)
+		if nonASCII {
+			status += " - " + unicodeNote("field.password", nfc)
+		}
+		selected, _ := parseModeFromSelection(modeSelect.Selected) // 0 is auto-detect
+		username, _ := trimInput(usernameEntry.Text, prefs.BoolWithFallback(prefTrimUsername, true))
+		username, _ = normalizeInput(username, nfc)
+		if modeNeedsUsername[selected] && username == "" {
+			statusLabel.SetText(tr("verify.username_required", selected))
+			return
+		}
+		if skipped := skippedModes(hash, username); len(skipped) > 0 {
+			status += " - " + tr("verify.modes_skipped", modeList(skipped))
+		}
+		statusLabel.SetText(status)
 
-		if strings.HasPrefix(hash, "$7$") {
-			if verifySCrypt(hash, password) {
-				resultLabel.SetText("PASS - Password matches this SCrypt hash")
-			} else {
-				resultLabel.SetText("FAIL - Password does NOT match this SCrypt hash")
+		candidates := detectHashModes(hash)
+		switch {
+		case strings.HasPrefix(hash, "$argon2") && argon2Variant(hash) != "argon2id":
+			resultLabel.SetText(tr("verify.argon2_unsupported", argon2Variant(hash)))
+			return
+		case len(candidates) == 0 && !isHex(hash) && !strings.HasPrefix(hash, "$"):
+			resultLabel.SetText(tr("verify.not_hex"))
+			return
+		case len(candidates) == 0 && isHex(hash):
+			resultLabel.SetText(tr("verify.hex_length", len(hash)))
+			return
+		}
+
+		verifyButton.Disable()
+		resultLabel.SetText(tr("verify.checking"))
+		go func() {
+			defer recoverPanic(showPanic(statusLabel, verifyButton.Enable))
+			mode, err := verifySelected(hash, username, password, selected)
+			switch {
+			case errors.Is(err, errSCryptDerive):
+				resultLabel.SetText(tr("verify.scrypt_failed", err))
+			case err != nil && strings.HasPrefix(hash, "$7$"):
+				resultLabel.SetText(tr("verify.scrypt_malformed", err))
+			case err != nil && strings.HasPrefix(hash, "$argon2"):
+				resultLabel.SetText(tr("verify.argon2_malformed", err))
+			case err != nil:
+				resultLabel.SetText(tr("error", err))
+			case mode != 0 && selected != 0 && mode != selected:
+				resultLabel.SetText(tr("verify.pass_other_mode", mode, modeName(mode), selected))
+			case mode != 0:
+				resultLabel.SetText(tr("verify.pass", mode, modeName(mode)))
+			case selected != 0 && !slices.Contains(candidates, selected):
+				resultLabel.SetText(tr("verify.fail_wrong_format", selected, modeList(candidates)))
+			default:
+				modes := candidates
+				if selected != 0 {
+					modes = []int{selected}
+				}
+				result := tr("verify.fail", modeList(candidates))
+				if username != "" && slices.ContainsFunc(modes, func(m int) bool { return tripleModes[m] }) {
+					result += "\n" + tr("verify.triple_hint")
+				}
+				// verifySCrypt got this far, so the hash itself is fine.
+				if slices.Contains(modes, 14) {
+					result += "\n" + tr("verify.scrypt_mismatch")
+				}
+				resultLabel.SetText(result)
+				if diff, ok := closestHexMode(hash, username, password, modes); ok {
+					setDiff(hexDiffSegments(hash, diff))
+				}
+			}
+			if err == nil && modeNeedsUsername[mode] {
+				rememberUsername(username)
+			}
+			verifyButton.Enable()
+		}()
+	}
+	verify = guard(statusLabel, verify)
+	verifyButton = widget.NewButton(tr("verify.button"), verify)
+	verifyButton.Importance = widget.HighImportance
+
+	hashEntry.addSubmitShortcut(verify)
+	usernameEntry.addSubmitShortcut(verify)
+	passwordEntry.addSubmitShortcut(verify)
+	// Enter moves from hash to username to password, then verifies.
+	hashEntry.OnSubmitted = func(string) { w.Canvas().Focus(usernameEntry) }
+	usernameEntry.OnSubmitted = func(string) { w.Canvas().Focus(passwordEntry) }
+	passwordEntry.OnSubmitted = func(string) { verify() }
+
+	pasteButton := widget.NewButton(tr("verify.paste"), func() {
+		text, err := readClipboard(w.Clipboard())
+		if err != nil {
+			statusLabel.SetText(tr("clipboard.unavailable"))
+			return
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			statusLabel.SetText(tr("verify.clipboard_empty"))
+			return
+		}
+		// A whole row copied from a query result: keep just the field that
+		// looks like a hash.
+		if len(detectHashModes(text)) == 0 {
+			if hash, field, fields, ok := findHashField(text); ok {
+				hashEntry.SetText(hash)
+				statusLabel.SetText(tr("verify.pasted_field", field, fields, len(hash)))
+				return
 			}
-		} else if strings.HasPrefix(hash, "$argon2") {
-			resultLabel.SetText("Argon2 verification not yet supported in verify tab")
-		} else {
-			resultLabel.SetText(fmt.Sprintf("Hash is %d chars (MD5=32, SHA1=40, SHA512=128) - use Generate tab to compare", len(hash)))
 		}
+		hashEntry.SetText(text)
+		statusLabel.SetText(tr("verify.pasted", len(text)))
 	})
-	verifyButton.Importance = widget.HighImportance
 
-	pasteButton := widget.NewButton("Paste from Clipboard", func() {
-		text := w.Clipboard().Content()
-		hashEntry.SetText(strings.TrimSpace(text))
-		statusLabel.SetText(fmt.Sprintf("Pasted %d chars (trimmed whitespace)", len(strings.TrimSpace(text))))
+	// setHash fills the hash entry from a dropped file; OnChanged then shows
+	// the detected mode.
+	setHash := func(hash, source string) {
+		hashEntry.SetText(hash)
+		statusLabel.SetText(tr("verify.loaded", len(hash), source))
+	}
+	w.SetOnDropped(func(_ fyne.Position, uris []fyne.URI) {
+		if len(uris) == 0 {
+			return
+		}
+		show()
+		uri := uris[0]
+		reader, err := storage.Reader(uri)
+		if err != nil {
+			statusLabel.SetText(tr("error.open_file", uri.Name(), err))
+			return
+		}
+		// Hash files are tiny; don't slurp something huge dropped by mistake.
+		data, err := io.ReadAll(io.LimitReader(reader, maxDroppedFileSize))
+		reader.Close()
+		if err != nil {
+			statusLabel.SetText(tr("error.read_file", uri.Name(), err))
+			return
+		}
+
+		lines := hashLines(data)
+		switch len(lines) {
+		case 0:
+			statusLabel.SetText(tr("verify.file_empty", uri.Name()))
+		case 1:
+			setHash(lines[0], uri.Name())
+		default:
+			var picker dialog.Dialog
+			list := widget.NewList(
+				func() int { return len(lines) },
+				func() fyne.CanvasObject { return widget.NewLabel("") },
+				func(id widget.ListItemID, obj fyne.CanvasObject) {
+					obj.(*widget.Label).SetText(lines[id])
+				},
+			)
+			list.OnSelected = func(id widget.ListItemID) {
+				setHash(lines[id], tr("verify.file_line", uri.Name(), id+1))
+				picker.Hide()
+			}
+			picker = dialog.NewCustom(tr("verify.pick_title", uri.Name(), len(lines)), tr("dialog.cancel"), list, w)
+			picker.Resize(fyne.NewSize(600, 400))
+			picker.Show()
+		}
+	})
+
+	// Several candidate passwords, one per line, tried in order against the
+	// hash. A multi-line entry can't mask its text, which the hint says.
+	candidatesEntry := widget.NewMultiLineEntry()
+	candidatesEntry.SetPlaceHolder(tr("verify.candidates_placeholder"))
+	candidatesEntry.SetMinRowsVisible(4)
+	candidatesProgress := widget.NewProgressBar()
+	candidatesProgress.Hide()
+
+	var cancelSearch context.CancelFunc
+	var tryAllButton, cancelButton *widget.Button
+	tryAllButton = widget.NewButton(tr("verify.try_all"), func() {
+		hash, junk := cleanHash(hashEntry.Text)
+		candidates := candidatePasswords(candidatesEntry.Text)
+		username, _ := trimInput(usernameEntry.Text, prefs.BoolWithFallback(prefTrimUsername, true))
+		username, _ = normalizeInput(username, prefs.Bool(prefNormalizeUnicode))
+		for i := range candidates {
+			candidates[i], _ = normalizeInput(candidates[i], prefs.Bool(prefNormalizeUnicode))
+		}
+		if hash == "" || len(candidates) == 0 {
+			statusLabel.SetText(tr("verify.candidates_required"))
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelSearch = cancel
+		tryAllButton.Disable()
+		cancelButton.Enable()
+		candidatesProgress.SetValue(0)
+		candidatesProgress.Show()
+		status := tr("verify.trying", len(candidates))
+		if junk > 0 {
+			status += " - " + tr("verify.hash_cleaned", junk)
+		}
+		statusLabel.SetText(status)
+		go func() {
+			defer cancel()
+			defer recoverPanic(showPanic(statusLabel, func() {
+				candidatesProgress.Hide()
+				tryAllButton.Enable()
+				cancelButton.Disable()
+			}))
+			// Without a username, modes that need one are skipped.
+			i, mode, err := findPassword(ctx, hash, username, candidates, func(done int) {
+				candidatesProgress.SetValue(float64(done) / float64(len(candidates)))
+			})
+			candidatesProgress.Hide()
+			tryAllButton.Enable()
+			cancelButton.Disable()
+
+			switch {
+			case errors.Is(err, context.Canceled):
+				statusLabel.SetText(tr("verify.try_cancelled"))
+			case err != nil:
+				resultLabel.SetText(tr("error", err))
+			case i < 0:
+				resultLabel.SetText(tr("verify.no_candidate", len(candidates)))
+			default:
+				resultLabel.SetText(tr("verify.candidate_match", i+1, mode))
+			}
+		}()
+	})
+	cancelButton = widget.NewButton(tr("dialog.cancel"), func() {
+		if cancelSearch != nil {
+			cancelSearch()
+		}
+	})
+	cancelButton.Disable()
+
+	candidatesHint := widget.NewLabel(tr("verify.candidates_hint"))
+	candidatesHint.Wrapping = fyne.TextWrapWord
+	candidatesPanel := container.NewVBox(
+		candidatesHint,
+		candidatesEntry,
+		container.NewHBox(tryAllButton, cancelButton),
+		candidatesProgress,
+	)
+
+	// Two hashes compared with each other rather than with a password,
+	// e.g. to check that two tools produced the same output.
+	compareEntryA := widget.NewEntry()
+	compareEntryA.SetPlaceHolder(tr("verify.compare_first"))
+	compareEntryB := widget.NewEntry()
+	compareEntryB.SetPlaceHolder(tr("verify.compare_second"))
+	ignoreCaseCheck := widget.NewCheck(tr("verify.compare_ignore_case"), nil)
+	ignoreCaseCheck.SetChecked(true)
+	compareResult := widget.NewLabel("")
+	compareButton := widget.NewButton(tr("verify.compare_button"), func() {
+		a, b := strings.TrimSpace(compareEntryA.Text), strings.TrimSpace(compareEntryB.Text)
+		switch {
+		case a == "" || b == "":
+			compareResult.SetText(tr("verify.compare_required"))
+		case compareHashes(a, b, ignoreCaseCheck.Checked):
+			compareResult.SetText(tr("verify.compare_equal"))
+		default:
+			compareResult.SetText(tr("verify.compare_different", len(a), len(b)))
+		}
+	})
+	comparePanel := container.NewVBox(
+		compareEntryA,
+		compareEntryB,
+		container.NewHBox(ignoreCaseCheck, layout.NewSpacer(), compareButton),
+		compareResult,
+	)
+
+	// See the Generate tab's reset about clearing passwords.
+	resetButton := widget.NewButton(tr("reset.button"), func() {
+		hashEntry.SetText("")
+		usernameEntry.SetText("")
+		passwordEntry.SetText("")
+		passwordEntry.mask()
+		modeSelect.SetSelectedIndex(0)
+		candidatesEntry.SetText("")
+		compareEntryA.SetText("")
+		compareEntryB.SetText("")
+		compareResult.SetText("")
+		resultLabel.SetText("")
+		setDiff(nil)
+		statusLabel.SetText(tr("reset.done"))
 	})
 
+	// As on the Generate tab, the paste button comes before the entries so
+	// Tab goes straight from hash to password.
 	content := container.NewVBox(
-		widget.NewLabel("Paste the hash from your database:"),
+		container.NewBorder(nil, nil, nil, pasteButton, widget.NewLabel(tr("verify.hash_label"))),
 		hashEntry,
-		container.NewHBox(pasteButton, layout.NewSpacer()),
-		widget.NewLabel("Password:"),
+		detailsLabel,
+		componentButtons,
+		widget.NewForm(widget.NewFormItem(tr("verify.mode_label"), modeSelect)),
+		widget.NewLabel(tr("verify.username_label")),
+		usernameEntry,
+		widget.NewLabel(tr("verify.password_label")),
 		passwordEntry,
 		layout.NewSpacer(),
-		verifyButton,
+		container.NewBorder(nil, nil, nil, resetButton, verifyButton),
+		widget.NewAccordion(
+			widget.NewAccordionItem(tr("verify.candidates"), candidatesPanel),
+			widget.NewAccordionItem(tr("verify.compare"), comparePanel),
+		),
 		widget.NewSeparator(),
 		resultLabel,
+		diffText,
 	)
 
-	return container.NewTabItem("Verify", content)
+	return container.NewTabItem(tr("verify.tab"), container.NewVScroll(content))
 }
 
-func main() {
-	a := app.New()
-	w := a.NewWindow("EQEmu Password Hasher")
-	w.Resize(fyne.NewSize(700, 520))
+// hexDiffSegments shows the stored hash above the one computed under
+// diff.Mode, with the differing characters in the error color, so a one
+// character typo stands out from a wrong password.
+func hexDiffSegments(stored string, diff hexDiff) []widget.RichTextSegment {
+	summary := "verify.hex_diff"
+	if diff.Count <= hexTypoMax {
+		summary = "verify.hex_diff_typo"
+	}
+	segments := []widget.RichTextSegment{&widget.TextSegment{
+		Text:  tr(summary, diff.Mode, diff.Count, len(stored), diff.First+1),
+		Style: widget.RichTextStyleParagraph,
+	}}
+	row := func(label, a, b string) {
+		segments = append(segments, &widget.TextSegment{Text: tr(label) + " ", Style: widget.RichTextStyleInline})
+		for _, run := range diffRuns(a, b) {
+			style := widget.RichTextStyleCodeInline
+			if run.Differ {
+				style.ColorName = theme.ColorNameError
+				style.TextStyle.Bold = true
+			}
+			segments = append(segments, &widget.TextSegment{Text: run.Text, Style: style})
+		}
+		// The last run ends the line.
+		segments[len(segments)-1].(*widget.TextSegment).Style.Inline = false
+	}
+	row("verify.diff_stored", stored, diff.Computed)
+	row("verify.diff_computed", diff.Computed, stored)
+	return segments
+}
 
-	statusLabel := widget.NewLabel("")
+// applyTheme switches the app between the light and dark themes, or back to
+// following the OS setting.
+func applyTheme(settings fyne.Settings, name string) {
+	switch name {
+	case themeLight:
+		settings.SetTheme(theme.LightTheme())
+	case themeDark:
+		settings.SetTheme(theme.DarkTheme())
+	default:
+		settings.SetTheme(theme.DefaultTheme())
+	}
+}
+
+// setBusyTitle adds activity to the window title so a long batch or
+// database operation can be followed from the taskbar. An empty activity
+// restores the plain title.
+func setBusyTitle(w fyne.Window, activity string) {
+	if activity == "" {
+		w.SetTitle(tr("app.title"))
+		return
+	}
+	w.SetTitle(tr("app.title_busy", tr("app.title"), activity))
+}
+
+func buildBatchTab(w fyne.Window, statusLabel *widget.Label) *container.TabItem {
+	progress := widget.NewProgressBar()
+	progress.Hide()
+	summaryLabel := widget.NewLabel("")
+	summaryLabel.Wrapping = fyne.TextWrapWord
+
+	// run verifies the rows in data and writes the report to out.
+	run := func(data []byte, out fyne.URIWriteCloser, done func()) {
+		defer done()
+		defer out.Close()
+		defer setBusyTitle(w, "")
+		defer recoverPanic(showPanic(statusLabel, progress.Hide))
+
+		progress.SetValue(0)
+		progress.Show()
+		setBusyTitle(w, tr("batch.verifying"))
+		var titled time.Time
+		summary, err := batchVerify(bytes.NewReader(data), out, func(n, total int) {
+			progress.SetValue(float64(n) / float64(total))
+			// Every row would flood the window manager on a big file.
+			if n == total || time.Since(titled) >= 250*time.Millisecond {
+				titled = time.Now()
+				setBusyTitle(w, tr("batch.title_progress", n, total))
+			}
+		})
+		progress.Hide()
+		if err != nil {
+			statusLabel.SetText(tr("batch.failed", err))
+			return
+		}
+		report := out.URI().Path()
+		if report == "" {
+			report = out.URI().String()
+		}
+		summaryLabel.SetText(tr("batch.summary",
+			summary.Total, summary.Elapsed, summary.Passed, summary.Failed, summary.Errors, report))
+		statusLabel.SetText(tr("batch.report_written", out.URI().Name()))
+	}
 
-	tabs := container.NewAppTabs(
-		buildGenerateTab(w, statusLabel),
-		buildVerifyTab(w, statusLabel),
+	resetButton := widget.NewButton(tr("reset.button"), func() {
+		summaryLabel.SetText("")
+		statusLabel.SetText(tr("reset.done"))
+	})
+
+	var verifyButton *widget.Button
+	verifyButton = widget.NewButton(tr("batch.button"), func() {
+		open := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil {
+				statusLabel.SetText(tr("error", err))
+				return
+			}
+			if reader == nil {
+				return // cancelled
+			}
+			data, err := io.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				statusLabel.SetText(tr("error.read_file", reader.URI().Name(), err))
+				return
+			}
+
+			save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+				if err != nil {
+					statusLabel.SetText(tr("error", err))
+					return
+				}
+				if writer == nil {
+					return // cancelled
+				}
+				verifyButton.Disable()
+				statusLabel.SetText(tr("batch.verifying"))
+				go run(data, writer, verifyButton.Enable)
+			}, w)
+			save.SetFileName(strings.TrimSuffix(reader.URI().Name(), reader.URI().Extension()) + "-report.csv")
+			save.Show()
+		}, w)
+		open.SetFilter(storage.NewExtensionFileFilter([]string{".csv", ".txt"}))
+		open.Show()
+	})
+	verifyButton.Importance = widget.HighImportance
+
+	helpLabel := widget.NewLabelWithStyle(tr("batch.help"),
+		fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+	helpLabel.Wrapping = fyne.TextWrapWord
+
+	content := container.NewVBox(
+		widget.NewLabel(tr("batch.intro")),
+		helpLabel,
+		container.NewHBox(verifyButton, layout.NewSpacer(), resetButton),
+		progress,
+		summaryLabel,
 	)
 
-	content := container.NewBorder(
-		widget.NewLabelWithStyle("EQEmu Login Account Password Hasher",
-			fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
-		statusLabel,
-		nil, nil,
-		tabs,
+	return container.NewTabItem(tr("batch.tab"), content)
+}
+
+// buildSettingsTab builds the Settings tab. relabel is called after the
+// language changes so the caller can rebuild the window in the new language.
+func buildSettingsTab(statusLabel *widget.Label, prefs fyne.Preferences, settings fyne.Settings, relabel func()) *container.TabItem {
+	languageNames := make([]string, len(languages))
+	for i, lang := range languages {
+		languageNames[i] = lang.Name
+	}
+	languageSelect := widget.NewSelect(languageNames, nil)
+	for i, lang := range languages {
+		if lang.Code == prefs.StringWithFallback(prefLanguage, defaultLanguage) {
+			languageSelect.SetSelectedIndex(i)
+		}
+	}
+	languageSelect.OnChanged = func(string) {
+		code := languages[languageSelect.SelectedIndex()].Code
+		if err := setLanguage(code); err != nil {
+			statusLabel.SetText(tr("error", err))
+			return
+		}
+		prefs.SetString(prefLanguage, code)
+		relabel()
+	}
+
+	// The radio shows translated names; the preference keeps the English
+	// theme constants so it survives a language change.
+	themes := []string{themeSystem, themeLight, themeDark}
+	themeNames := []string{tr("theme.system"), tr("theme.light"), tr("theme.dark")}
+	themeRadio := widget.NewRadioGroup(themeNames, func(name string) {
+		for i, themeName := range themeNames {
+			if name == themeName {
+				prefs.SetString(prefTheme, themes[i])
+				applyTheme(settings, themes[i])
+			}
+		}
+	})
+	themeRadio.Horizontal = true
+	themeRadio.Required = true
+	for i, name := range themes {
+		if name == prefs.StringWithFallback(prefTheme, themeSystem) {
+			themeRadio.SetSelected(themeNames[i])
+		}
+	}
+
+	clearEntry := widget.NewEntry()
+	clearEntry.SetText(strconv.Itoa(prefs.IntWithFallback(prefClipboardClearSeconds, defaultClipboardClearSeconds)))
+	clearEntry.OnChanged = func(text string) {
+		seconds, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil || seconds < 0 {
+			statusLabel.SetText(tr("settings.clipboard_invalid"))
+			return
+		}
+		prefs.SetInt(prefClipboardClearSeconds, seconds)
+		if seconds == 0 {
+			statusLabel.SetText(tr("settings.clipboard_never"))
+		} else {
+			statusLabel.SetText(tr("settings.clipboard_after", seconds))
+		}
+	}
+
+	scryptCapEntry := widget.NewEntry()
+	scryptCapEntry.SetText(strconv.Itoa(prefs.IntWithFallback(prefSCryptMaxMemoryMiB, defaultSCryptMaxMemoryMiB)))
+	scryptCapEntry.OnChanged = func(text string) {
+		mib, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil || mib < 16 {
+			statusLabel.SetText(tr("settings.scrypt_cap_invalid"))
+			return
+		}
+		prefs.SetInt(prefSCryptMaxMemoryMiB, mib)
+		setSCryptMaxMemoryMiB(mib)
+		statusLabel.SetText(tr("settings.scrypt_cap_set", mib))
+	}
+
+	// Advanced: only for verifiers other than libsodium, which always
+	// writes 16-byte salts.
+	saltLengthEntry := widget.NewEntry()
+	saltLengthEntry.SetText(strconv.Itoa(prefs.IntWithFallback(prefArgon2SaltLength, defaultArgon2SaltLen)))
+	saltLengthEntry.OnChanged = func(text string) {
+		length, err := strconv.Atoi(strings.TrimSpace(text))
+		if err == nil {
+			err = checkArgon2SaltLen(length)
+		}
+		if err != nil {
+			statusLabel.SetText(tr("settings.salt_length_invalid", argon2MinSaltLen, argon2MaxSaltLen))
+			return
+		}
+		prefs.SetInt(prefArgon2SaltLength, length)
+		setArgon2SaltLen(length)
+		if length == defaultArgon2SaltLen {
+			statusLabel.SetText(tr("settings.salt_length_default"))
+		} else {
+			statusLabel.SetText(tr("settings.salt_length_set", length))
+		}
+	}
+
+	maxLengthEntry := widget.NewEntry()
+	maxLengthEntry.SetText(strconv.Itoa(prefs.IntWithFallback(prefMaxPasswordLength, defaultMaxPasswordLength)))
+	maxLengthEntry.OnChanged = func(text string) {
+		length, err := strconv.Atoi(strings.TrimSpace(text))
+		if err != nil || length < 0 {
+			statusLabel.SetText(tr("settings.max_length_invalid"))
+			return
+		}
+		prefs.SetInt(prefMaxPasswordLength, length)
+		setMaxPasswordLength(length)
+		if length == 0 {
+			statusLabel.SetText(tr("settings.max_length_none"))
+		} else {
+			statusLabel.SetText(tr("settings.max_length_set", length))
+		}
+	}
+
+	trimUsernameCheck := widget.NewCheck(tr("settings.trim_username"), func(on bool) {
+		prefs.SetBool(prefTrimUsername, on)
+	})
+	trimUsernameCheck.SetChecked(prefs.BoolWithFallback(prefTrimUsername, true))
+
+	trimPasswordCheck := widget.NewCheck(tr("settings.trim_password"), func(on bool) {
+		prefs.SetBool(prefTrimPassword, on)
+	})
+	trimPasswordCheck.SetChecked(prefs.Bool(prefTrimPassword))
+
+	normalizeCheck := widget.NewCheck(tr("settings.normalize_unicode"), func(on bool) {
+		prefs.SetBool(prefNormalizeUnicode, on)
+	})
+	normalizeCheck.SetChecked(prefs.Bool(prefNormalizeUnicode))
+
+	showSaltCheck := widget.NewCheck(tr("settings.show_salt"), func(on bool) {
+		prefs.SetBool(prefShowSalt, on)
+	})
+	showSaltCheck.SetChecked(prefs.Bool(prefShowSalt))
+	autoCopyCheck := widget.NewCheck(tr("settings.auto_copy"), func(on bool) {
+		prefs.SetBool(prefAutoCopy, on)
+	})
+	autoCopyCheck.SetChecked(prefs.Bool(prefAutoCopy))
+	uppercaseHexCheck := widget.NewCheck(tr("settings.uppercase_hex"), func(on bool) {
+		setUppercaseHex(on)
+		prefs.SetBool(prefUppercaseHex, on)
+	})
+	uppercaseHexCheck.SetChecked(prefs.Bool(prefUppercaseHex))
+
+	forkModesCheck := widget.NewCheck(tr("settings.fork_modes_check"), func(on bool) {
+		prefs.SetBool(prefShowForkModes, on)
+	})
+	forkModesCheck.SetChecked(prefs.Bool(prefShowForkModes))
+	experimentalModesCheck := widget.NewCheck(tr("settings.experimental_modes_check"), func(on bool) {
+		prefs.SetBool(prefShowExperimentalModes, on)
+	})
+	experimentalModesCheck.SetChecked(prefs.Bool(prefShowExperimentalModes))
+	forkModesItem := widget.NewFormItem(tr("settings.fork_modes"), container.NewVBox(forkModesCheck, experimentalModesCheck))
+	forkModesItem.HintText = tr("settings.fork_modes_hint")
+
+	trimItem := widget.NewFormItem(tr("settings.input"), container.NewVBox(trimUsernameCheck, trimPasswordCheck, normalizeCheck))
+	trimItem.HintText = tr("settings.input_hint")
+
+	clearItem := widget.NewFormItem(tr("settings.clear_clipboard"), clearEntry)
+	clearItem.HintText = tr("settings.clear_clipboard_hint")
+
+	auditPathEntry := widget.NewEntry()
+	auditPathEntry.SetText(prefs.StringWithFallback(prefAuditLogPath, defaultAuditLogPath()))
+	auditCheck := widget.NewCheck(tr("settings.audit_enable"), nil)
+	auditCheck.SetChecked(prefs.Bool(prefAuditLog))
+	if auditCheck.Checked {
+		auditPathEntry.Disable()
+	}
+	auditCheck.OnChanged = func(on bool) {
+		path := ""
+		if on {
+			path = strings.TrimSpace(auditPathEntry.Text)
+		}
+		if err := setAuditLogFile(path); err != nil {
+			statusLabel.SetText(tr("settings.audit_error", err))
+			auditCheck.SetChecked(false)
+			return
+		}
+		prefs.SetBool(prefAuditLog, on)
+		if on {
+			prefs.SetString(prefAuditLogPath, path)
+			auditPathEntry.Disable()
+			statusLabel.SetText(tr("settings.audit_on", path))
+		} else {
+			auditPathEntry.Enable()
+			statusLabel.SetText(tr("settings.audit_off"))
+		}
+	}
+	auditItem := widget.NewFormItem(tr("settings.audit"), container.NewVBox(auditCheck, auditPathEntry))
+	auditItem.HintText = tr("settings.audit_hint")
+
+	var selfTestButton *widget.Button
+	selfTestButton = widget.NewButton(tr("settings.selftest_button"), func() {
+		selfTestButton.Disable()
+		statusLabel.SetText(tr("settings.selftest_running"))
+		go func() {
+			defer selfTestButton.Enable()
+			defer recoverPanic(showPanic(statusLabel, nil))
+			var failed []string
+			for _, m := range modeTable {
+				if err := selfTestMode(m.Number); err != nil {
+					failed = append(failed, strconv.Itoa(m.Number))
+				}
+			}
+			if len(failed) > 0 {
+				statusLabel.SetText(tr("settings.selftest_failed", strings.Join(failed, ", ")))
+			} else {
+				statusLabel.SetText(tr("settings.selftest_passed", len(modeTable)))
+			}
+		}()
+	})
+	selfTestItem := widget.NewFormItem(tr("settings.selftest"), container.NewHBox(selfTestButton))
+	selfTestItem.HintText = tr("settings.selftest_hint")
+
+	maxLengthItem := widget.NewFormItem(tr("settings.max_length"), maxLengthEntry)
+	maxLengthItem.HintText = tr("settings.max_length_hint")
+
+	scryptCapItem := widget.NewFormItem(tr("settings.scrypt_cap"), scryptCapEntry)
+	scryptCapItem.HintText = tr("settings.scrypt_cap_hint")
+	saltLengthItem := widget.NewFormItem(tr("settings.salt_length"), saltLengthEntry)
+	saltLengthItem.HintText = tr("settings.salt_length_hint")
+
+	content := container.NewVBox(
+		widget.NewForm(
+			widget.NewFormItem(tr("settings.language"), languageSelect),
+			widget.NewFormItem(tr("settings.theme"), themeRadio),
+			forkModesItem,
+			trimItem,
+			widget.NewFormItem(tr("settings.output"), container.NewVBox(showSaltCheck, autoCopyCheck, uppercaseHexCheck)),
+			clearItem,
+			maxLengthItem,
+			scryptCapItem,
+			saltLengthItem,
+			auditItem,
+			selfTestItem,
+		),
 	)
 
-	w.SetContent(container.NewPadded(content))
+	return container.NewTabItem(tr("settings.tab"), content)
+}
+
+// applyAppConfig copies the values set in config.json into the preferences,
+// so the file wins on every start while the Settings tab still edits them
+// for the session.
+func applyAppConfig(cfg appConfig, prefs fyne.Preferences) {
+	cfg.applyKDFParams()
+	if cfg.Mode != nil {
+		prefs.SetInt(prefMode, *cfg.Mode)
+	}
+	if cfg.Argon2SaltLength != nil {
+		prefs.SetInt(prefArgon2SaltLength, *cfg.Argon2SaltLength)
+	}
+	if cfg.MaxPasswordLength != nil {
+		prefs.SetInt(prefMaxPasswordLength, *cfg.MaxPasswordLength)
+	}
+	if cfg.NormalizeUnicode != nil {
+		prefs.SetBool(prefNormalizeUnicode, *cfg.NormalizeUnicode)
+	}
+	if cfg.ShowForkModes != nil {
+		prefs.SetBool(prefShowForkModes, *cfg.ShowForkModes)
+	}
+	if cfg.ShowExperimentalModes != nil {
+		prefs.SetBool(prefShowExperimentalModes, *cfg.ShowExperimentalModes)
+	}
+	if cfg.Language != nil {
+		prefs.SetString(prefLanguage, *cfg.Language)
+	}
+	if cfg.Theme != nil {
+		prefs.SetString(prefTheme, *cfg.Theme)
+	}
+}
+
+func main() {
+	if cliRequested(os.Args[1:]) {
+		os.Exit(runCLI(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
+	a := app.NewWithID("com.eqemu.passwordhasher")
+	prefs := a.Preferences()
+	cfg, cfgPath, cfgErr := loadAppConfig(appConfigPaths())
+	if cfgErr == nil {
+		applyAppConfig(cfg, prefs)
+	}
+	// Verify-only is for support staff who check a player's password but
+	// must never produce a hash: the Generate tab, with the database,
+	// provisioning and API panels, is not built at all.
+	verifyOnly := verifyOnlyRequested(os.Args[1:]) || cfg.VerifyOnly != nil && *cfg.VerifyOnly
+	applyTheme(a.Settings(), prefs.StringWithFallback(prefTheme, themeSystem))
+	if mib := prefs.IntWithFallback(prefSCryptMaxMemoryMiB, defaultSCryptMaxMemoryMiB); mib >= 16 {
+		setSCryptMaxMemoryMiB(mib)
+	}
+	if length := prefs.IntWithFallback(prefMaxPasswordLength, defaultMaxPasswordLength); length >= 0 {
+		setMaxPasswordLength(length)
+	}
+	if length := prefs.IntWithFallback(prefArgon2SaltLength, defaultArgon2SaltLen); checkArgon2SaltLen(length) == nil {
+		setArgon2SaltLen(length)
+	}
+	setUppercaseHex(prefs.Bool(prefUppercaseHex))
+	if err := setLanguage(prefs.StringWithFallback(prefLanguage, defaultLanguage)); err != nil {
+		prefs.SetString(prefLanguage, defaultLanguage)
+	}
+	if prefs.Bool(prefAuditLog) {
+		if err := setAuditLogFile(prefs.StringWithFallback(prefAuditLogPath, defaultAuditLogPath())); err != nil {
+			prefs.SetBool(prefAuditLog, false)
+		}
+	}
+
+	w := a.NewWindow(tr("app.title"))
+	w.Resize(fyne.NewSize(
+		float32(prefs.FloatWithFallback(prefWindowWidth, 700)),
+		float32(prefs.FloatWithFallback(prefWindowHeight, 520)),
+	))
+	w.SetCloseIntercept(func() {
+		size := w.Canvas().Size()
+		prefs.SetFloat(prefWindowWidth, float64(size.Width))
+		prefs.SetFloat(prefWindowHeight, float64(size.Height))
+		w.Close()
+	})
+
+	// build creates the window content in the current language. Changing the
+	// language rebuilds everything and returns to the Settings tab.
+	var build func(selectTab int)
+	build = func(selectTab int) {
+		uiGeneration.Add(1)
+		statusLabel := widget.NewLabel("")
+		// The mode selected on the Generate tab and shown on the Modes tab.
+		selectedMode := binding.NewInt()
+		if cfgPath != "" {
+			statusLabel.SetText(tr("config.loaded", cfgPath))
+		}
+
+		tabs := container.NewAppTabs()
+		if verifyOnly {
+			statusLabel.SetText(tr("app.verify_only"))
+		} else {
+			tabs.Append(buildGenerateTab(w, statusLabel, prefs, selectedMode))
+		}
+		var verifyTab *container.TabItem
+		verifyTab = buildVerifyTab(w, statusLabel, prefs, func() { tabs.Select(verifyTab) })
+		tabs.Append(verifyTab)
+		tabs.Append(buildBatchTab(w, statusLabel))
+		tabs.Append(buildReferenceTab(prefs, selectedMode))
+		tabs.Append(buildSettingsTab(statusLabel, prefs, a.Settings(), func() {
+			build(len(tabs.Items) - 1)
+		}))
+		tabs.SelectIndex(selectTab)
+
+		content := container.NewBorder(
+			widget.NewLabelWithStyle(tr("app.heading"),
+				fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			statusLabel,
+			nil, nil,
+			tabs,
+		)
+
+		w.SetTitle(tr("app.title"))
+		w.SetContent(container.NewPadded(content))
+	}
+	build(0)
+	if cfgErr != nil {
+		dialog.ShowError(errors.New(tr("config.invalid", cfgErr)), w)
+	}
+
 	w.ShowAndRun()
 }
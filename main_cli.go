@@ -0,0 +1,12 @@
+//go:build cli
+
+package main
+
+import "os"
+
+// Built with -tags cli, the binary is the command-line hasher only and does
+// not link Fyne, so it builds on headless servers without X11 or OpenGL
+// headers. -cli is accepted but not required.
+func main() {
+	os.Exit(runCLI(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
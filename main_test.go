@@ -0,0 +1,448 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Known-answer vectors for every mode. The hex modes were computed with an
+// independent MD5/SHA implementation; the argon2id and scrypt vectors come from
+// libsodium (crypto_pwhash and crypto_pwhash_scryptsalsa208sha256_ll) using
+// the fixed salts below, and libsodium's *_str_verify accepts both strings.
+const (
+	goldenUsername = "Gearheart"
+	goldenPassword = "Kaladim#4Ever"
+)
+
+var goldenVectors = map[int]string{
+	1:  "53858fa103148966ded9229dcead4f5b",
+	2:  "b6f9446eeae59fa582b640fb259e9894",
+	3:  "a323387808f125bf88692621e259a195",
+	4:  "73247ced3d15674fe568523b0f37ae84",
+	5:  "f8c66a12c873f797467dcef11abffb84aae520ac",
+	6:  "62083f5c00d214313e7c0d256ffa883e95db0c4a",
+	7:  "58122e2613c6bc1aa286f6b0aeb1c2c6a81c8c39",
+	8:  "7504f2604da38730777cda7027e7e19438c3980a",
+	9:  "f9d475421bf442b6b92befa6931b8e8d4df06fda8952dc847985c7c7b452b4268a63ac5ba2b49ea1a33ecc0b392a535cd2fd8f930fcfd9ab691abbc2344cbf92",
+	10: "53d614860d30559b7f877623bb4c26ab00b38f9436d6aa1bd27215c4627fef6584c8e2ceebb8231128fdd1d5484adacf6d0223b15aa9263320a8fb3f514a98f2",
+	11: "b08ff1d4b33a0f26aea621b0f39aa9574bc0ae12cfd1c70b785847d3c1fbc908bcb53a50b49691d242380770747699cd4445132b6189a03b638b23a996f8875b",
+	12: "b474dbb285659a33f081d0f09c6454f4201038ddfe2267840f566c245750da3550352c33d247783715cda8ccac6b46df3de705383eedc7a0d6c7da335ad4678a",
+	13: "$argon2id$v=19$m=65536,t=2,p=1$AAECAwQFBgcICQoLDA0ODw$JSYLtExpEDLASlDIanNns9CIqZQxk26uZJnOpHInGbY",
+	14: "$7$C6..../.....2U.1EE/4Q.07ck0AoU1D.F2GA/3JMl3MYV4PkF5Sw/$G7wNE2v4MDldfrlmnyeni2h0XGc9s8RLGcbfrHEBw.C",
+	15: "2e69007a18c3d0854850d1069ac8e019061b0eac80e980ba71940ef985622972",
+	16: "286df59f0cfe953af574d61b514e51fecab8795a4efe114f46aa9acba1348102",
+	17: "1dc152df7127edf07a33f5259e4e110edb2aa607672c00d6d61ce08bab9b03ca",
+	18: "696aecf03caf828a104fdc34e2328566505f7326f4f73b9ed1676c4fa1d0b548",
+	19: "9c5e22929779130ea31eb1ab16c4e4e483b6cb7ca43f9489b4170ee59244b023",
+	20: "1f138023783edef63f9d37642bf9506ade6c5ff1da082f5910912b734feb449a",
+	21: "3aae5612d2b861799c560b1b57560f5cd6aa4bacd79338af89ff3b490541741b",
+	22: "aa013c325c6d22003b0ce3e2a49dab5628bc9f551c556a5cc03edffaf4f097d6",
+	23: "3d14bde3492c2a4e3f90fdf3e3412b6481590913d139b6894135cbb63027b1397f079d2839c6a1fb6f4551de1ee9e8c049b91d55104193429a7024b3c4c26847",
+	24: "65cf1f1182347beb9402168db086c6900ff27cb57f90c44b52ff3cc7770be07e421d8392e0cef9e75f6d3cce6868299ee68b370dd1bbbc30f6cd3dbc16ecca3c",
+	25: "0186210dd7117551c7daa469011da44d798073d77f690308011ee274f16d964399cd3f94f9e362b4e2aa5186dadd1be40bbfe5803a781ede562380f7856e5d12",
+	26: "89ea6932f2851e2769111504033858dd8f2c7cbbb316407e8a808d49bdc132768755fe729dcec5d2f3fc0ffad3136a184d2e8a2c7a30d7cf5208bad4d3b1e2e5",
+}
+
+// goldenSalt returns n sequential bytes 0x00, 0x01, ... used for the salted
+// vectors.
+func goldenSalt(n int) []byte {
+	salt := make([]byte, n)
+	for i := range salt {
+		salt[i] = byte(i)
+	}
+	return salt
+}
+
+func TestGoldenVectors(t *testing.T) {
+	for mode := 1; mode <= len(modeOptions); mode++ {
+		want, ok := goldenVectors[mode]
+		if !ok {
+			t.Errorf("mode %d: no golden vector", mode)
+			continue
+		}
+
+		var got string
+		var err error
+		switch mode {
+		case 13:
+			got, err = hashArgon2WithSalt(goldenPassword, goldenSalt(16))
+		case 14:
+			got, err = hashSCryptWithSalt(goldenPassword, goldenSalt(32))
+		default:
+			got, err = eqcryptHash(goldenUsername, goldenPassword, mode)
+		}
+		if err != nil {
+			t.Errorf("mode %d: %v", mode, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("mode %d:\n got  %s\n want %s", mode, got, want)
+		}
+	}
+}
+
+func TestSaltedModesUseRandomSalt(t *testing.T) {
+	prefixes := map[int]string{
+		13: "$argon2id$v=19$m=65536,t=2,p=1$",
+		14: "$7$C6..../....",
+	}
+	if len(prefixes) != len(saltedModes) {
+		t.Errorf("salted modes %v, want 13 and 14", saltedModes)
+	}
+	for mode, prefix := range prefixes {
+		if !saltedModes[mode] {
+			t.Errorf("mode %d is not in saltedModes", mode)
+		}
+		first, err := eqcryptHash(goldenUsername, goldenPassword, mode)
+		if err != nil {
+			t.Fatalf("mode %d: %v", mode, err)
+		}
+		second, err := eqcryptHash(goldenUsername, goldenPassword, mode)
+		if err != nil {
+			t.Fatalf("mode %d: %v", mode, err)
+		}
+		if first == second {
+			t.Errorf("mode %d: two hashes of the same password are identical", mode)
+		}
+		if !strings.HasPrefix(first, prefix) {
+			t.Errorf("mode %d: %q does not start with %q", mode, first, prefix)
+		}
+	}
+}
+
+func TestUnsupportedMode(t *testing.T) {
+	for _, mode := range []int{0, -1, 99} {
+		if _, err := eqcryptHash(goldenUsername, goldenPassword, mode); err == nil {
+			t.Errorf("mode %d: expected an error", mode)
+		}
+	}
+}
+
+func TestEmptyPassword(t *testing.T) {
+	for mode := 1; mode <= len(modeOptions); mode++ {
+		if _, err := eqcryptHash(goldenUsername, "", mode); !errors.Is(err, errEmptyPassword) {
+			t.Errorf("mode %d: got %v, want errEmptyPassword", mode, err)
+		}
+	}
+}
+
+func TestUsernameWarning(t *testing.T) {
+	for mode := 1; mode <= len(modeOptions); mode++ {
+		warn := usernameWarning(mode, "odd:name")
+		if modeUsesColon[mode] && warn == "" {
+			t.Errorf("mode %d: expected a warning for a username containing ':'", mode)
+		}
+		if !modeUsesColon[mode] && warn != "" {
+			t.Errorf("mode %d: unexpected warning %q", mode, warn)
+		}
+		if warn := usernameWarning(mode, "plainname"); warn != "" {
+			t.Errorf("mode %d: unexpected warning %q for a plain username", mode, warn)
+		}
+	}
+}
+
+func TestExportAllModes(t *testing.T) {
+	modes := []int{1, 2, 5, 13}
+	table := exportAllModes(goldenUsername, goldenPassword, modes, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	for _, want := range []string{
+		`username "Gearheart"`,
+		"Generated 2024-01-02 03:04:05",
+		goldenVectors[1],
+		goldenVectors[2],
+		goldenVectors[5],
+		"$argon2id$v=19$m=65536,t=2,p=1$",
+	} {
+		if !strings.Contains(table, want) {
+			t.Errorf("table is missing %q:\n%s", want, table)
+		}
+	}
+
+	table = exportAllModes("", goldenPassword, modes, time.Now())
+	if strings.Count(table, "(skipped: username required)") != 1 {
+		t.Errorf("expected mode 2 to be skipped without a username:\n%s", table)
+	}
+}
+
+func TestVisibleModeOptions(t *testing.T) {
+	for _, tc := range []struct {
+		fork, experimental, security bool
+		count, def                   int
+	}{
+		{false, false, true, standardModeCount, 13},
+		{false, false, false, standardModeCount, 6},
+		{true, false, true, forkModeCount, 13},
+		{true, true, true, len(modeOptions), 13},
+		{false, true, true, len(modeOptions), 13},
+	} {
+		options := visibleModeOptions(tc.fork, tc.experimental, tc.security)
+		if len(options) != tc.count {
+			t.Errorf("fork=%v experimental=%v: got %d options, want %d", tc.fork, tc.experimental, len(options), tc.count)
+		}
+		for i, option := range options {
+			if marked := strings.HasSuffix(option, tr("generate.build_default")); marked != (i+1 == tc.def) {
+				t.Errorf("security=%v: option %q marked=%v", tc.security, option, marked)
+			}
+			if mode, ok := parseModeFromSelection(option); !ok || mode != i+1 {
+				t.Errorf("option %q does not parse as mode %d", option, i+1)
+			}
+		}
+	}
+	if strings.Contains(modeOptions[5], "default") {
+		t.Error("visibleModeOptions modified modeOptions")
+	}
+}
+
+func TestParseModeFromSelection(t *testing.T) {
+	for _, sel := range []string{"", " ", "0 - none", "99 - none", "x - MD5", "14"} {
+		mode, ok := parseModeFromSelection(sel)
+		if want := sel == "14"; ok != want || (ok && mode != 14) {
+			t.Errorf("%q: got %d, %v", sel, mode, ok)
+		}
+	}
+}
+
+func TestParseModeNumber(t *testing.T) {
+	options := visibleModeOptions(false, false, true)
+	for mode := 1; mode <= standardModeCount; mode++ {
+		got, err := parseModeNumber(" "+strconv.Itoa(mode)+" ", options)
+		if err != nil || got != mode {
+			t.Errorf("%d: got %d, %v", mode, got, err)
+		}
+		if i := modeOptionIndex(options, mode); i != mode-1 {
+			t.Errorf("mode %d: option index %d", mode, i)
+		}
+	}
+	for _, bad := range []string{"", "0", "15", "-1", "eleven", "11.5"} {
+		if _, err := parseModeNumber(bad, options); err == nil || !strings.Contains(err.Error(), "1 to 14") {
+			t.Errorf("%q: got %v", bad, err)
+		}
+	}
+	if mode, err := parseModeNumber("17", visibleModeOptions(true, false, true)); err != nil || mode != 17 {
+		t.Errorf("fork mode 17 with fork modes shown: %d, %v", mode, err)
+	}
+}
+
+func TestModeDescription(t *testing.T) {
+	for mode := 1; mode <= len(modeOptions); mode++ {
+		desc := modeDescription(mode, true)
+		if !strings.Contains(desc, modeOptions[mode-1]) || strings.Contains(desc, fmt.Sprintf("mode.%d", mode)) {
+			t.Errorf("mode %d: incomplete description %q", mode, desc)
+		}
+		if isDefault := strings.Contains(desc, tr("mode.build_default")); isDefault != (mode == defaultModeWithSecurity) {
+			t.Errorf("mode %d: build default note = %v", mode, isDefault)
+		}
+	}
+	if modeDescription(0, true) != "" {
+		t.Error("expected no description for mode 0")
+	}
+}
+
+func TestNormalizeInput(t *testing.T) {
+	composed, decomposed := "caf\u00e9", "cafe\u0301"
+
+	if got, nonASCII := normalizeInput("secret", true); got != "secret" || nonASCII {
+		t.Errorf("ASCII input: got %q, %v", got, nonASCII)
+	}
+	if got, nonASCII := normalizeInput(decomposed, false); got != decomposed || !nonASCII {
+		t.Errorf("without nfc: got %q, %v, want input unchanged and flagged", got, nonASCII)
+	}
+	if got, nonASCII := normalizeInput(decomposed, true); got != composed || !nonASCII {
+		t.Errorf("with nfc: got %q, %v, want %q", got, nonASCII, composed)
+	}
+
+	// The two forms look identical but hash differently, which is the
+	// whole reason for the option.
+	if hashSHA256(composed) == hashSHA256(decomposed) {
+		t.Error("composed and decomposed forms hash the same")
+	}
+}
+
+func TestPasswordFromFile(t *testing.T) {
+	tests := []struct {
+		data         string
+		stripNewline bool
+		want         string
+	}{
+		{"token\n", true, "token"},
+		{"token\r\n", true, "token"},
+		{"token\n\n", true, "token\n"},
+		{"token\r", true, "token\r"},
+		{" token \n", true, " token "},
+		{"token\n", false, "token\n"},
+		{"\xff\xfe\x00raw", false, "\xff\xfe\x00raw"},
+	}
+	for _, tt := range tests {
+		if got, err := passwordFromFile([]byte(tt.data), tt.stripNewline); got != tt.want || err != nil {
+			t.Errorf("passwordFromFile(%q, %v) = %q, %v, want %q", tt.data, tt.stripNewline, got, err, tt.want)
+		}
+	}
+	if _, err := passwordFromFile([]byte("\n"), true); err == nil {
+		t.Error("newline-only file accepted")
+	}
+
+	// The bytes are hashed as they are, even when not valid UTF-8.
+	password, _ := passwordFromFile([]byte("\xff\xfe"), false)
+	if got, _ := eqcryptHash("", password, 1); got != hashMD5("\xff\xfe") {
+		t.Errorf("raw bytes hashed as %s", got)
+	}
+
+	if data, err := readPasswordFile(strings.NewReader(strings.Repeat("x", maxPasswordFileSize))); err != nil || len(data) != maxPasswordFileSize {
+		t.Errorf("%d-byte file: %d bytes, %v", maxPasswordFileSize, len(data), err)
+	}
+	if _, err := readPasswordFile(strings.NewReader(strings.Repeat("x", maxPasswordFileSize+1))); err == nil {
+		t.Error("oversized file accepted")
+	}
+}
+
+func TestModeTable(t *testing.T) {
+	if len(modeOptions) != len(modeTable) {
+		t.Fatalf("%d options for %d modes", len(modeOptions), len(modeTable))
+	}
+	for i, m := range modeTable {
+		if m.Number != i+1 {
+			t.Errorf("modeTable[%d] is mode %d, want %d", i, m.Number, i+1)
+		}
+		if m.HashFunc == nil {
+			t.Errorf("mode %d has no HashFunc", m.Number)
+			continue
+		}
+		if saltedModes[m.Number] {
+			continue // two hashes never match anyway
+		}
+
+		// NeedsUsername must agree with whether the username changes the
+		// hash.
+		a, _ := m.HashFunc("alice", goldenPassword)
+		b, _ := m.HashFunc("bob", goldenPassword)
+		if m.NeedsUsername != (a != b) {
+			t.Errorf("mode %d: NeedsUsername is %v but the username changes the hash: %v", m.Number, m.NeedsUsername, a != b)
+		}
+	}
+}
+
+func TestModeAlgorithm(t *testing.T) {
+	for mode, want := range map[int]string{
+		1:  "MD5 (hex)",
+		8:  "SHA1 (hex)",
+		12: "SHA512 (hex)",
+		13: "Argon2id (PHC $argon2id$)",
+		14: "SCrypt (escrypt $7$)",
+		17: "SHA256 (hex)",
+	} {
+		m, _ := lookupMode(mode)
+		if got := m.algorithm(); got != want {
+			t.Errorf("mode %d: got %q, want %q", mode, got, want)
+		}
+	}
+}
+
+func TestArgon2SaltLength(t *testing.T) {
+	defer func(old int) { argon2SaltLen = old }(argon2SaltLen)
+
+	for _, length := range []int{argon2MinSaltLen, defaultArgon2SaltLen, argon2MaxSaltLen} {
+		argon2SaltLen = length
+		hash, err := hashArgon2(goldenPassword)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", length, err)
+		}
+		if _, salt, _, err := parseArgon2PHC(hash); err != nil || len(salt) != length {
+			t.Errorf("%d bytes: got a %d-byte salt, %v", length, len(salt), err)
+		}
+	}
+
+	argon2SaltLen = argon2MinSaltLen - 1
+	if _, err := hashArgon2(goldenPassword); err == nil {
+		t.Error("expected a salt below the minimum to be refused")
+	}
+}
+
+// The Settings tab changes the KDF settings while hashes run in the
+// background; run with -race.
+func TestKDFSettingsConcurrentChange(t *testing.T) {
+	defer func(saltLen int, a argon2Params, s scryptParams) {
+		argon2SaltLen, argon2Generate, scryptGenerate = saltLen, a, s
+	}(argon2SaltLen, argon2Generate, scryptGenerate)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			setArgon2SaltLen(argon2MinSaltLen + i%8)
+			setGenerateParams(argon2Interactive, scryptParams{LogN: 10 + uint32(i%2), R: 8, P: 1})
+		}
+	}()
+	for i := 0; i < 3; i++ {
+		if _, err := hashArgon2(goldenPassword); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := hashSCrypt(goldenPassword); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
+
+func TestPasswordLength(t *testing.T) {
+	defer func(old int) { maxPasswordLength = old }(maxPasswordLength)
+
+	long := strings.Repeat("correct horse battery staple ", 345)[:10000]
+
+	maxPasswordLength = defaultMaxPasswordLength
+	if _, err := eqcryptHash(goldenUsername, long, 14); !errors.Is(err, errPasswordTooLong) {
+		t.Errorf("10k-character password with the default cap: got %v, want errPasswordTooLong", err)
+	}
+	if _, err := eqcryptHash(goldenUsername, strings.Repeat("é", defaultMaxPasswordLength), 1); err != nil {
+		t.Errorf("cap counts bytes rather than characters: %v", err)
+	}
+
+	// With the cap raised, every mode hashes the whole password: changing
+	// only its last character must change the hash and fail verification.
+	maxPasswordLength = 10000
+	other := long[:len(long)-1] + "!"
+	for _, m := range modeTable {
+		hash, err := eqcryptHash(goldenUsername, long, m.Number)
+		if err != nil {
+			t.Errorf("mode %d: %v", m.Number, err)
+			continue
+		}
+		if ok, err := verifyHash(hash, goldenUsername, long, m.Number); !ok || err != nil {
+			t.Errorf("mode %d: round trip failed: %v, %v", m.Number, ok, err)
+		}
+		if ok, _ := verifyHash(hash, goldenUsername, other, m.Number); ok {
+			t.Errorf("mode %d: password truncated, last character ignored", m.Number)
+		}
+	}
+
+	maxPasswordLength = 0
+	if _, err := eqcryptHash(goldenUsername, long+long, 5); err != nil {
+		t.Errorf("no limit: %v", err)
+	}
+}
+
+func TestCheckHashFormat(t *testing.T) {
+	for mode, hash := range goldenVectors {
+		if err := checkHashFormat(mode, hash); err != nil {
+			t.Errorf("mode %d: %v", mode, err)
+		}
+	}
+	for _, bad := range []struct {
+		mode int
+		hash string
+	}{
+		{1, goldenVectors[5]},          // SHA1 length from an MD5 mode
+		{5, goldenVectors[5][:39]},     // truncated
+		{9, goldenVectors[15]},         // SHA256 from a SHA512 mode
+		{13, goldenVectors[14]},        // SCrypt from the Argon2 mode
+		{14, goldenVectors[14] + "$x"}, // extra section
+		{2, ""},
+	} {
+		if err := checkHashFormat(bad.mode, bad.hash); !errors.Is(err, errWrongOutputFormat) {
+			t.Errorf("mode %d with %q: got %v, want errWrongOutputFormat", bad.mode, bad.hash, err)
+		}
+	}
+}
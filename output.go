@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// outputFormat selects how a generated hash is presented in the Generate
+// tab's output field, and so what gets copied.
+type outputFormat int
+
+const (
+	formatHash outputFormat = iota
+	formatSQLUpdate
+	formatJSON
+	formatSQLInsert
+	formatEnv
+)
+
+// outputFormatOptions returns the labels for the output format dropdown, in
+// outputFormat order.
+func outputFormatOptions() []string {
+	return []string{
+		tr("format.hash"),
+		tr("format.sql_update"),
+		tr("format.json"),
+		tr("format.sql_insert"),
+		tr("format.env"),
+	}
+}
+
+// defaultEnvVarName is the variable formatEnv assigns the hash to, e.g. in a
+// Docker .env file. envVarName is the one in use, set on the Generate tab.
+const defaultEnvVarName = "ACCOUNT_PASSWORD_HASH"
+
+var envVarName = defaultEnvVarName
+
+// uppercaseHex prints MD5/SHA hashes in uppercase hex, for legacy account
+// import tools that compare hashes as exact strings. The loginserver writes
+// lowercase, so only the displayed formats change, never the SQL ones that
+// go into login_accounts; verifying ignores hex case either way.
+var uppercaseHex bool
+
+// outputSettingsMu guards envVarName and uppercaseHex, which the GUI can
+// change while serve or batch workers format hashes. Outside tests they are
+// only accessed through the setters below, outputHash and formatOutput.
+var outputSettingsMu sync.RWMutex
+
+func setEnvVarName(name string) {
+	outputSettingsMu.Lock()
+	defer outputSettingsMu.Unlock()
+	envVarName = name
+}
+
+func setUppercaseHex(on bool) {
+	outputSettingsMu.Lock()
+	defer outputSettingsMu.Unlock()
+	uppercaseHex = on
+}
+
+// outputHash applies uppercaseHex to a hash about to be shown or printed.
+func outputHash(hash string) string {
+	outputSettingsMu.RLock()
+	upper := uppercaseHex
+	outputSettingsMu.RUnlock()
+	if upper && isHex(hash) {
+		return strings.ToUpper(hash)
+	}
+	return hash
+}
+
+// checkEnvVarName accepts the names a POSIX shell can assign.
+func checkEnvVarName(name string) error {
+	if name == "" {
+		return fmt.Errorf("variable name is empty")
+	}
+	for i, c := range name {
+		if c != '_' && !(c >= 'A' && c <= 'Z') && !(c >= 'a' && c <= 'z') && !(i > 0 && c >= '0' && c <= '9') {
+			return fmt.Errorf("invalid character %q at position %d of the variable name (use A-Z, a-z, 0-9 and _, not starting with a digit)", c, i+1)
+		}
+	}
+	return nil
+}
+
+// usesAccountName reports whether the format includes the account name even
+// for modes that don't hash it.
+func (f outputFormat) usesAccountName() bool {
+	return f == formatSQLUpdate || f == formatSQLInsert
+}
+
+// Stands in for the account name in SQL when no username was entered, so
+// the statement is obviously incomplete rather than matching an empty name.
+const sqlAccountPlaceholder = "ACCOUNT_NAME"
+
+// sqlQuote returns s as a MySQL string literal. Hashes never contain quotes
+// or backslashes, but account names typed by hand might. The control
+// characters mysql_real_escape_string escapes are escaped too, although
+// checkAccountName keeps them out of account names.
+func sqlQuote(s string) string {
+	return "'" + sqlEscaper.Replace(s) + "'"
+}
+
+var sqlEscaper = strings.NewReplacer(
+	`\`, `\\`, `'`, `\'`, "\x00", `\0`, "\n", `\n`, "\r", `\r`, "\x1a", `\Z`,
+)
+
+// checkAccountName refuses account names with control characters, which no
+// client can type at the login screen and which only get in by pasting.
+func checkAccountName(name string) error {
+	for i, r := range []rune(name) {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("account name has a control character (%U) at position %d", r, i+1)
+		}
+	}
+	return nil
+}
+
+// formatOutput renders a generated hash in the given format. The SQL formats
+// write to target's table with the same columns the Database panel uses, and
+// keep hex in lowercase as the loginserver writes it.
+func formatOutput(mode int, target accountTarget, username, hash string, format outputFormat) string {
+	if format != formatSQLUpdate && format != formatSQLInsert {
+		hash = outputHash(hash)
+	}
+	account := username
+	if account == "" {
+		account = sqlAccountPlaceholder
+	}
+
+	switch format {
+	case formatSQLUpdate:
+		return fmt.Sprintf("UPDATE %s SET account_password = %s WHERE account_name = %s;",
+			target.table(), sqlQuote(hash), sqlQuote(account))
+	case formatSQLInsert:
+		if target == targetAdmin {
+			return fmt.Sprintf("INSERT INTO login_server_admins (account_name, account_password, first_name, last_name, email, registration_date, registration_ip_address) VALUES (%s, %s, '', '', '', NOW(), '');",
+				sqlQuote(account), sqlQuote(hash))
+		}
+		return fmt.Sprintf("INSERT INTO login_accounts (account_name, account_password, account_email, source_loginserver, last_ip_address, last_login_date, created_at) VALUES (%s, %s, '', 'local', '', NOW(), NOW());",
+			sqlQuote(account), sqlQuote(hash))
+	case formatJSON:
+		data, err := json.Marshal(cliResult{Mode: mode, Username: username, Hash: hash})
+		if err != nil {
+			return hash
+		}
+		return string(data)
+	case formatEnv:
+		// Single quotes keep the $ of Argon2 and SCrypt hashes literal.
+		outputSettingsMu.RLock()
+		name := envVarName
+		outputSettingsMu.RUnlock()
+		return name + "=" + shellQuote(hash)
+	default:
+		return hash
+	}
+}
+
+// outputComment says what a hash is, for whoever it is handed to, e.g.
+// "mode 14 (SCrypt) hash for account 'bob' generated 2024-05-01T12:00:00Z".
+// It stays in English like the CSV reports, since it ends up in files.
+func outputComment(mode int, target accountTarget, username string, when time.Time) string {
+	comment := fmt.Sprintf("mode %d (%s) hash", mode, modeName(mode))
+	if username != "" {
+		noun := "account"
+		if target == targetAdmin {
+			noun = "admin"
+		}
+		comment += fmt.Sprintf(" for %s '%s'", noun, username)
+	}
+	comment += " generated " + when.Format(time.RFC3339)
+	// Keep it on one line so the rest can't escape the comment.
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(comment)
+}
+
+// commentedOutput is formatOutput preceded by an outputComment line in the
+// comment syntax of the format: "--" for SQL and "#", as in a config file,
+// for a bare hash or a variable. JSON has no comments, so it gets a "comment" member.
+func commentedOutput(mode int, target accountTarget, username, hash string, format outputFormat, when time.Time) string {
+	comment := outputComment(mode, target, username, when)
+	switch format {
+	case formatJSON:
+		data, err := json.Marshal(cliResult{Mode: mode, Username: username, Hash: outputHash(hash), Comment: comment})
+		if err != nil {
+			return formatOutput(mode, target, username, hash, format)
+		}
+		return string(data)
+	case formatSQLUpdate, formatSQLInsert:
+		return "-- " + comment + "\n" + formatOutput(mode, target, username, hash, format)
+	default:
+		return "# " + comment + "\n" + formatOutput(mode, target, username, hash, format)
+	}
+}
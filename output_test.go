@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatOutput(t *testing.T) {
+	hash := goldenVectors[6]
+
+	tests := []struct {
+		name     string
+		target   accountTarget
+		username string
+		format   outputFormat
+		want     string
+	}{
+		{"hash", targetAccount, goldenUsername, formatHash, hash},
+		{"update", targetAccount, goldenUsername, formatSQLUpdate,
+			"UPDATE login_accounts SET account_password = '" + hash + "' WHERE account_name = 'Gearheart';"},
+		{"update admin", targetAdmin, goldenUsername, formatSQLUpdate,
+			"UPDATE login_server_admins SET account_password = '" + hash + "' WHERE account_name = 'Gearheart';"},
+		{"update no username", targetAccount, "", formatSQLUpdate,
+			"UPDATE login_accounts SET account_password = '" + hash + "' WHERE account_name = 'ACCOUNT_NAME';"},
+		{"update quoting", targetAccount, `o'brien\`, formatSQLUpdate,
+			"UPDATE login_accounts SET account_password = '" + hash + `' WHERE account_name = 'o\'brien\\';`},
+		{"insert", targetAccount, goldenUsername, formatSQLInsert,
+			"INSERT INTO login_accounts (account_name, account_password, account_email, source_loginserver, last_ip_address, last_login_date, created_at) VALUES ('Gearheart', '" + hash + "', '', 'local', '', NOW(), NOW());"},
+		{"insert admin", targetAdmin, goldenUsername, formatSQLInsert,
+			"INSERT INTO login_server_admins (account_name, account_password, first_name, last_name, email, registration_date, registration_ip_address) VALUES ('Gearheart', '" + hash + "', '', '', '', NOW(), '');"},
+	}
+	for _, tt := range tests {
+		if got := formatOutput(6, tt.target, tt.username, hash, tt.format); got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSQLQuote(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Gearheart", `'Gearheart'`},
+		{"o'brien", `'o\'brien'`},
+		{`back\slash`, `'back\\slash'`},
+		{`\'`, `'\\\''`},
+		{"x'; DROP TABLE login_accounts; --", `'x\'; DROP TABLE login_accounts; --'`},
+		{"a\x00b\nc\rd\x1ae", `'a\0b\nc\rd\Ze'`},
+	}
+	for _, tt := range tests {
+		if got := sqlQuote(tt.in); got != tt.want {
+			t.Errorf("sqlQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCheckAccountName(t *testing.T) {
+	for _, name := range []string{"Gearheart", "o'brien", `back\slash`, "semi;colon", "Zoë", ""} {
+		if err := checkAccountName(name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+	for _, name := range []string{"tab\there", "new\nline", "nul\x00", "esc\x1b[31m", "del\x7f", "c1\u0085"} {
+		if err := checkAccountName(name); err == nil {
+			t.Errorf("%q: no error", name)
+		}
+	}
+}
+
+func TestFormatOutputJSON(t *testing.T) {
+	hash := goldenVectors[14]
+	out := formatOutput(14, targetAccount, goldenUsername, hash, formatJSON)
+
+	var got cliResult
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out)
+	}
+	if want := (cliResult{Mode: 14, Username: goldenUsername, Hash: hash}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestFormatOutputEnv(t *testing.T) {
+	defer func() { envVarName = defaultEnvVarName }()
+
+	hash := goldenVectors[14]
+	if got, want := formatOutput(14, targetAccount, goldenUsername, hash, formatEnv), "ACCOUNT_PASSWORD_HASH='"+hash+"'"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	envVarName = "EQ_HASH"
+	if got, want := formatOutput(14, targetAccount, goldenUsername, "a'b", formatEnv), `EQ_HASH='a'\''b'`; got != want {
+		t.Errorf("quoting: got %s, want %s", got, want)
+	}
+
+	for _, name := range []string{"A", "_x", "HASH_2"} {
+		if err := checkEnvVarName(name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "2X", "MY-HASH", "A B", "É"} {
+		if err := checkEnvVarName(name); err == nil {
+			t.Errorf("%q: no error", name)
+		}
+	}
+}
+
+func TestFormatOutputUppercaseHex(t *testing.T) {
+	defer func() { uppercaseHex = false }()
+	uppercaseHex = true
+
+	upper := strings.ToUpper(goldenVectors[5])
+	if got := formatOutput(5, targetAccount, "", goldenVectors[5], formatHash); got != upper {
+		t.Errorf("hex: got %s, want %s", got, upper)
+	}
+	// SQL goes into login_accounts, which the loginserver compares in
+	// lowercase.
+	for _, format := range []outputFormat{formatSQLUpdate, formatSQLInsert} {
+		if got := formatOutput(5, targetAccount, goldenUsername, goldenVectors[5], format); !strings.Contains(got, "'"+goldenVectors[5]+"'") {
+			t.Errorf("SQL format %d: %s", format, got)
+		}
+	}
+	if got := provisionSQL([]provisionEntry{{Mode: 5, Username: goldenUsername, Hash: goldenVectors[5]}}, formatSQLInsert, time.Now()); strings.Contains(got, upper) {
+		t.Errorf("provisioning SQL has uppercase hex:\n%s", got)
+	}
+	// Argon2 and SCrypt hashes are base64, where case matters.
+	if got := formatOutput(14, targetAccount, "", goldenVectors[14], formatHash); got != goldenVectors[14] {
+		t.Errorf("SCrypt hash changed to %s", got)
+	}
+	if ok, err := verifyHash(upper, "", goldenPassword, 5); !ok || err != nil {
+		t.Errorf("uppercase output does not verify: %v, %v", ok, err)
+	}
+}
+
+func TestUsesAccountName(t *testing.T) {
+	for f := formatHash; f <= formatEnv; f++ {
+		want := f == formatSQLUpdate || f == formatSQLInsert
+		if got := f.usesAccountName(); got != want {
+			t.Errorf("format %d: usesAccountName = %v", f, got)
+		}
+	}
+}
+
+func TestOutputFormatOptions(t *testing.T) {
+	if got := len(outputFormatOptions()); got != int(formatEnv)+1 {
+		t.Errorf("%d options, want one per outputFormat", got)
+	}
+}
+
+func TestCommentedOutput(t *testing.T) {
+	hash := goldenVectors[14]
+	when := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	comment := "mode 14 (SCrypt) hash for account 'Gearheart' generated 2024-05-01T12:00:00Z"
+
+	if got, want := commentedOutput(14, targetAccount, goldenUsername, hash, formatHash, when), "# "+comment+"\n"+hash; got != want {
+		t.Errorf("hash:\n got %q\nwant %q", got, want)
+	}
+	got := commentedOutput(14, targetAccount, goldenUsername, hash, formatSQLUpdate, when)
+	if want := "-- " + comment + "\n" + formatOutput(14, targetAccount, goldenUsername, hash, formatSQLUpdate); got != want {
+		t.Errorf("SQL:\n got %q\nwant %q", got, want)
+	}
+
+	var result cliResult
+	if err := json.Unmarshal([]byte(commentedOutput(14, targetAccount, goldenUsername, hash, formatJSON, when)), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Comment != comment || result.Hash != hash {
+		t.Errorf("JSON: got %+v", result)
+	}
+
+	t.Cleanup(func() { uppercaseHex = false })
+	uppercaseHex = true
+	result = cliResult{}
+	if err := json.Unmarshal([]byte(commentedOutput(1, targetAccount, "", goldenVectors[1], formatJSON, when)), &result); err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.ToUpper(goldenVectors[1]); result.Hash != want {
+		t.Errorf("JSON with uppercase hex: hash %q, want %q", result.Hash, want)
+	}
+	uppercaseHex = false
+
+	if got := outputComment(1, targetAdmin, "a\nDROP TABLE x;", when); strings.Contains(got, "\n") || !strings.Contains(got, "admin 'a DROP") {
+		t.Errorf("admin comment with newline: %q", got)
+	}
+	if got := outputComment(1, targetAccount, "", when); got != "mode 1 (MD5) hash generated 2024-05-01T12:00:00Z" {
+		t.Errorf("comment without username: %q", got)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+)
+
+// errPanic marks an error made from a recovered panic, which can only be a
+// bug here rather than bad input.
+var errPanic = errors.New("internal error")
+
+// panicError turns a recovered panic value into an error wrapping errPanic.
+// The stack trace goes to the standard logger (stderr), where it is still
+// available for a bug report.
+func panicError(v any) error {
+	log.Printf("recovered panic: %v\n%s", v, debug.Stack())
+	return fmt.Errorf("%w: %v", errPanic, v)
+}
+
+// recoverPanic stops a panic and passes it to report as an error. It must be
+// deferred directly, e.g. defer recoverPanic(report), for recover to work.
+func recoverPanic(report func(error)) {
+	if v := recover(); v != nil {
+		report(panicError(v))
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRecoverPanic(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	var reported error
+	func() {
+		defer recoverPanic(func(err error) { reported = err })
+		var modes map[int]modeInfo
+		modes[99] = modeInfo{} // assignment to a nil map panics
+	}()
+	if !errors.Is(reported, errPanic) || !strings.Contains(reported.Error(), "nil map") {
+		t.Errorf("reported %v", reported)
+	}
+
+	reported = nil
+	func() {
+		defer recoverPanic(func(err error) { reported = err })
+	}()
+	if reported != nil {
+		t.Errorf("reported %v without a panic", reported)
+	}
+}
+
+func TestWithContextPanic(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	_, err := withContext(context.Background(), func() (string, error) {
+		panic("future mode bug")
+	})
+	if !errors.Is(err, errPanic) || !strings.Contains(err.Error(), "future mode bug") {
+		t.Errorf("got %v, want an errPanic", err)
+	}
+}
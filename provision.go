@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// provisionEntry is one generated account queued in the Generate tab's
+// provisioning batch, to be written out with the others as one .sql file.
+type provisionEntry struct {
+	Mode     int
+	Target   accountTarget
+	Username string
+	Hash     string
+}
+
+// addProvision appends e to entries, replacing an earlier entry for the same
+// account and table so regenerating a password doesn't produce two
+// statements for one account. replaced reports whether that happened.
+func addProvision(entries []provisionEntry, e provisionEntry) (updated []provisionEntry, replaced bool) {
+	for i, old := range entries {
+		if old.Target == e.Target && old.Username == e.Username {
+			entries[i] = e
+			return entries, true
+		}
+	}
+	return append(entries, e), false
+}
+
+// provisionSQL renders entries as a SQL script of INSERT statements, or
+// UPDATE statements for format formatSQLUpdate, each preceded by its
+// outputComment. The statements run in one transaction so a failure part
+// way through leaves no accounts half-provisioned.
+func provisionSQL(entries []provisionEntry, format outputFormat, when time.Time) string {
+	if format != formatSQLUpdate {
+		format = formatSQLInsert
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- EQEmu account provisioning: %d accounts, exported %s\n", len(entries), when.Format(time.RFC3339))
+	b.WriteString("START TRANSACTION;\n")
+	for _, e := range entries {
+		b.WriteString("\n")
+		b.WriteString(commentedOutput(e.Mode, e.Target, e.Username, e.Hash, format, when))
+		b.WriteString("\n")
+	}
+	b.WriteString("\nCOMMIT;\n")
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddProvision(t *testing.T) {
+	var entries []provisionEntry
+	entries, replaced := addProvision(entries, provisionEntry{Mode: 14, Username: "alice", Hash: "a1"})
+	if replaced || len(entries) != 1 {
+		t.Fatalf("first add: %v, replaced %v", entries, replaced)
+	}
+	entries, _ = addProvision(entries, provisionEntry{Mode: 14, Target: targetAdmin, Username: "alice", Hash: "a2"})
+	entries, replaced = addProvision(entries, provisionEntry{Mode: 13, Username: "alice", Hash: "a3"})
+	if !replaced || len(entries) != 2 || entries[0].Hash != "a3" || entries[1].Hash != "a2" {
+		t.Errorf("re-adding an account: %v, replaced %v", entries, replaced)
+	}
+}
+
+func TestProvisionSQL(t *testing.T) {
+	when := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	entries := []provisionEntry{
+		{Mode: 14, Target: targetAccount, Username: goldenUsername, Hash: goldenVectors[14]},
+		{Mode: 13, Target: targetAdmin, Username: "o'brien", Hash: goldenVectors[13]},
+	}
+
+	got := provisionSQL(entries, formatHash, when)
+	for _, want := range []string{
+		"-- EQEmu account provisioning: 2 accounts, exported 2024-05-01T12:00:00Z\nSTART TRANSACTION;\n",
+		commentedOutput(14, targetAccount, goldenUsername, goldenVectors[14], formatSQLInsert, when),
+		formatOutput(13, targetAdmin, "o'brien", goldenVectors[13], formatSQLInsert),
+		"\nCOMMIT;\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("script missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "INSERT INTO") != 2 || strings.Contains(got, "UPDATE") {
+		t.Errorf("expected two INSERTs:\n%s", got)
+	}
+
+	if got := provisionSQL(entries, formatSQLUpdate, when); strings.Count(got, "UPDATE ") != 2 {
+		t.Errorf("expected two UPDATEs:\n%s", got)
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+// Most usernames remembered for the username dropdowns.
+const maxRecentUsernames = 20
+
+// addRecent returns list with item moved or added to the front, keeping at
+// most max entries. The list is not modified.
+func addRecent(list []string, item string, max int) []string {
+	recent := []string{item}
+	for _, existing := range list {
+		if existing != item && len(recent) < max {
+			recent = append(recent, existing)
+		}
+	}
+	return recent
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAddRecent(t *testing.T) {
+	list := []string{"alice", "bob", "carol"}
+
+	if got := addRecent(list, "dave", 3); !slices.Equal(got, []string{"dave", "alice", "bob"}) {
+		t.Errorf("new item: got %q", got)
+	}
+	if got := addRecent(list, "carol", 3); !slices.Equal(got, []string{"carol", "alice", "bob"}) {
+		t.Errorf("existing item: got %q", got)
+	}
+	if got := addRecent(nil, "alice", 3); !slices.Equal(got, []string{"alice"}) {
+		t.Errorf("empty list: got %q", got)
+	}
+	if !slices.Equal(list, []string{"alice", "bob", "carol"}) {
+		t.Errorf("input modified: %q", list)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// encryptionModeEnum is the loginserver's name for a mode in encryption.h,
+// e.g. "EncryptionModeSHAPassUser" for mode 6, derived from the mode's label.
+// Fork-only modes have no name there and get "".
+func encryptionModeEnum(m modeInfo) string {
+	if m.Number > standardModeCount {
+		return ""
+	}
+	name := map[string]string{
+		"md5": "MD5", "sha1": "SHA", "sha512": "SHA512", "argon2": "Argon2", "scrypt": "SCrypt",
+	}[m.family()]
+	switch {
+	case strings.Contains(m.Label, "(password:username)"):
+		name += "PassUser"
+	case strings.Contains(m.Label, "(username:password)"):
+		name += "UserPass"
+	case strings.Contains(m.Label, "Triple"):
+		name += "Triple"
+	}
+	return "EncryptionMode" + name
+}
+
+// needsSecurity reports whether the loginserver only offers a mode when built
+// with ENABLE_SECURITY, which links libsodium.
+func (m modeInfo) needsSecurity() bool {
+	family := m.family()
+	return family == "argon2" || family == "scrypt"
+}
+
+// referenceModes returns the modes the Modes tab lists, with or without the
+// fork-only and experimental ones, in mode order.
+func referenceModes(showForkModes, showExperimentalModes bool) []modeInfo {
+	return modeTable[:visibleModeCount(showForkModes, showExperimentalModes)]
+}
+
+// modeReference explains in Markdown how the loginserver refers to a mode
+// and what it expects stored, for the Modes tab. enableSecurity marks the
+// default mode of the build chosen on the Generate tab.
+func modeReference(mode int, enableSecurity bool) string {
+	m, ok := lookupMode(mode)
+	if !ok {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**\n\n", modeOptions[mode-1])
+	fmt.Fprintf(&b, "%s\n\n", tr("mode.setting", mode))
+	if enum := encryptionModeEnum(m); enum != "" {
+		fmt.Fprintf(&b, "%s\n\n", tr("reference.enum", enum))
+	} else if m.experimental() {
+		fmt.Fprintf(&b, "%s\n\n", tr("reference.experimental"))
+	} else {
+		fmt.Fprintf(&b, "%s\n\n", tr("reference.fork"))
+	}
+	fmt.Fprintf(&b, "%s\n\n", tr("reference.stored", m.algorithm()))
+	if m.NeedsUsername {
+		fmt.Fprintf(&b, "%s\n\n", tr("reference.username_required"))
+	} else {
+		fmt.Fprintf(&b, "%s\n\n", tr("reference.username_unused"))
+	}
+	if m.needsSecurity() {
+		fmt.Fprintf(&b, "%s\n\n", tr("reference.needs_security"))
+	}
+	fmt.Fprintf(&b, "%s\n\n", tr(fmt.Sprintf("mode.%d", mode)))
+	switch mode {
+	case defaultModeWithSecurity:
+		fmt.Fprintf(&b, "%s\n\n", tr("reference.default_with_security"))
+	case defaultModeWithoutSecurity:
+		fmt.Fprintf(&b, "%s\n\n", tr("reference.default_without_security"))
+	}
+	if mode == buildDefaultMode(enableSecurity) {
+		fmt.Fprintf(&b, "%s\n\n", tr("mode.build_default"))
+	}
+	return strings.TrimSuffix(b.String(), "\n\n")
+}
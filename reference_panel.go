@@ -0,0 +1,60 @@
+//go:build !cli
+
+package main
+
+import (
+	"slices"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/widget"
+)
+
+// buildReferenceTab builds the Modes tab, which lists the encryption modes
+// next to how the loginserver names them. selectedMode is shared with the
+// Generate tab, so picking a mode in either selects it in both.
+func buildReferenceTab(prefs fyne.Preferences, selectedMode binding.Int) *container.TabItem {
+	hint := widget.NewLabelWithStyle(tr("reference.hint"), fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+	hint.Wrapping = fyne.TextWrapWord
+
+	modes := referenceModes(prefs.Bool(prefShowForkModes), prefs.Bool(prefShowExperimentalModes))
+	detail := widget.NewRichText()
+	detail.Wrapping = fyne.TextWrapWord
+	showDetail := func() {
+		mode, _ := selectedMode.Get()
+		detail.ParseMarkdown(modeReference(mode, prefs.BoolWithFallback(prefEnableSecurity, true)))
+	}
+
+	list := widget.NewList(
+		func() int { return len(modes) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(modeOptions[modes[id].Number-1])
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		selectedMode.Set(modes[id].Number)
+	}
+	selectedMode.AddListener(binding.NewDataListener(func() {
+		mode, _ := selectedMode.Get()
+		if i := slices.IndexFunc(modes, func(m modeInfo) bool { return m.Number == mode }); i >= 0 {
+			list.Select(i)
+		}
+		showDetail()
+	}))
+
+	// Follow the fork and experimental modes settings and the Generate tab's
+	// ENABLE_SECURITY setting.
+	addPrefsListener(prefs, func() {
+		if shown := referenceModes(prefs.Bool(prefShowForkModes), prefs.Bool(prefShowExperimentalModes)); len(modes) != len(shown) {
+			modes = shown
+			list.Refresh()
+		}
+		showDetail()
+	})
+
+	split := container.NewHSplit(list, container.NewVScroll(detail))
+	split.Offset = 0.4
+	return container.NewTabItem(tr("reference.tab"), container.NewBorder(hint, nil, nil, nil, split))
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncryptionModeEnum(t *testing.T) {
+	// The EncryptionMode enum in EQEmu's loginserver/encryption.h.
+	want := []string{
+		"EncryptionModeMD5", "EncryptionModeMD5PassUser", "EncryptionModeMD5UserPass", "EncryptionModeMD5Triple",
+		"EncryptionModeSHA", "EncryptionModeSHAPassUser", "EncryptionModeSHAUserPass", "EncryptionModeSHATriple",
+		"EncryptionModeSHA512", "EncryptionModeSHA512PassUser", "EncryptionModeSHA512UserPass", "EncryptionModeSHA512Triple",
+		"EncryptionModeArgon2", "EncryptionModeSCrypt",
+	}
+	for _, m := range modeTable {
+		got := encryptionModeEnum(m)
+		if m.Number > standardModeCount {
+			if got != "" {
+				t.Errorf("fork mode %d: got %q", m.Number, got)
+			}
+			continue
+		}
+		if got != want[m.Number-1] {
+			t.Errorf("mode %d: got %q, want %q", m.Number, got, want[m.Number-1])
+		}
+	}
+}
+
+func TestModeReference(t *testing.T) {
+	if got := len(referenceModes(false, false)); got != standardModeCount {
+		t.Errorf("%d modes without forks", got)
+	}
+	if got := len(referenceModes(true, false)); got != forkModeCount {
+		t.Errorf("%d modes with forks", got)
+	}
+	if got := len(referenceModes(true, true)); got != len(modeTable) {
+		t.Errorf("%d modes with experimental modes", got)
+	}
+
+	text := modeReference(13, true)
+	for _, want := range []string{`"mode": 13`, "EncryptionModeArgon2", "ENABLE_SECURITY", tr("mode.build_default")} {
+		if !strings.Contains(text, want) {
+			t.Errorf("mode 13 reference lacks %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(modeReference(13, false), tr("mode.build_default")) {
+		t.Error("mode 13 marked as the default without ENABLE_SECURITY")
+	}
+	if text := modeReference(6, false); !strings.Contains(text, tr("reference.default_without_security")) || !strings.Contains(text, tr("reference.username_required")) {
+		t.Errorf("mode 6 reference:\n%s", text)
+	}
+	if text := modeReference(15, true); !strings.Contains(text, tr("reference.fork")) {
+		t.Errorf("mode 15 reference:\n%s", text)
+	}
+	if text := modeReference(22, true); !strings.Contains(text, tr("reference.experimental")) || strings.Contains(text, tr("reference.fork")) {
+		t.Errorf("mode 22 reference:\n%s", text)
+	}
+	if modeReference(0, true) != "" {
+		t.Error("mode 0 has a reference")
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// scryptParams are the cost parameters stored in an escrypt $7$ header.
+type scryptParams struct {
+	LogN uint32 // N = 2^LogN
+	R    uint32
+	P    uint32
+}
+
+func (p scryptParams) N() uint64 {
+	return uint64(1) << p.LogN
+}
+
+// memory returns the approximate memory scrypt needs for these parameters,
+// 128*N*r bytes, saturating rather than overflowing for absurd values.
+func (p scryptParams) memory() uint64 {
+	if p.LogN >= 64-7 || p.R == 0 {
+		return math.MaxUint64
+	}
+	perR := uint64(128) << p.LogN
+	if uint64(p.R) > math.MaxUint64/perR {
+		return math.MaxUint64
+	}
+	return perR * uint64(p.R)
+}
+
+// Default for scryptMaxMemory, in MiB. libsodium's SENSITIVE preset needs
+// 1 GiB, so that is the most a loginserver should ever be configured with.
+const defaultSCryptMaxMemoryMiB = 1024
+
+// scryptMaxMemory is the largest memory requirement, in bytes, that
+// hashSCryptWithParams will attempt. The GUI sets it from Settings through
+// setSCryptMaxMemoryMiB.
+var scryptMaxMemory uint64 = defaultSCryptMaxMemoryMiB << 20
+
+func setSCryptMaxMemoryMiB(mib int) {
+	kdfSettingsMu.Lock()
+	defer kdfSettingsMu.Unlock()
+	scryptMaxMemory = uint64(mib) << 20
+}
+
+// checkMemory refuses parameters whose memory requirement exceeds
+// scryptMaxMemory, before scrypt tries to allocate it.
+func (p scryptParams) checkMemory() error {
+	kdfSettingsMu.RLock()
+	limit := scryptMaxMemory
+	kdfSettingsMu.RUnlock()
+	if need := p.memory(); need > limit {
+		return fmt.Errorf("SCrypt %v needs %s of memory, over the %d MiB cap",
+			p, formatMiB(need), limit>>20)
+	}
+	return nil
+}
+
+// The smallest scrypt cost hashes are generated with: N=1024, r=8, p=1, which
+// is what libsodium's pickparams makes of crypto_pwhash_scryptsalsa208sha256
+// OPSLIMIT_MIN and MEMLIMIT_MIN. Existing hashes below it still verify.
+var scryptMinimum = scryptParams{LogN: 10, R: 8, P: 1}
+
+// checkMinimum refuses parameters cheaper than scryptMinimum.
+func (p scryptParams) checkMinimum() error {
+	if p.P < scryptMinimum.P {
+		return fmt.Errorf("SCrypt p=%d is below the minimum of p=%d", p.P, scryptMinimum.P)
+	}
+	if p.memory() < scryptMinimum.memory() {
+		return fmt.Errorf("SCrypt %v needs only %s of memory, below the minimum of %s (N=%d, r=%d)",
+			p, formatMiB(p.memory()), formatMiB(scryptMinimum.memory()), scryptMinimum.N(), scryptMinimum.R)
+	}
+	return nil
+}
+
+// belowInteractive reports whether p is cheaper in memory or total work than
+// the loginserver's INTERACTIVE defaults.
+func (p scryptParams) belowInteractive() bool {
+	work := func(q scryptParams) uint64 { return q.memory() / 128 * uint64(q.P) }
+	return p.memory() < scryptInteractive.memory() || work(p) < work(scryptInteractive)
+}
+
+// formatMiB formats a byte count in MiB for error messages.
+func formatMiB(bytes uint64) string {
+	if bytes == math.MaxUint64 {
+		return "more than 16 EiB"
+	}
+	return fmt.Sprintf("%d MiB", bytes>>20)
+}
+
+func (p scryptParams) String() string {
+	return fmt.Sprintf("N=%d, r=%d, p=%d", p.N(), p.R, p.P)
+}
+
+// decode64Uint32 inverts encode64Uint32, decoding a little-endian value of
+// the given bit width from the escrypt base64 alphabet.
+func decode64Uint32(src string, bits int) (uint32, error) {
+	chars := (bits + 5) / 6
+	if len(src) != chars {
+		return 0, fmt.Errorf("expected %d characters for a %d-bit value, got %d", chars, bits, len(src))
+	}
+	var value uint32
+	for i := 0; i < chars; i++ {
+		c := strings.IndexByte(itoa64, src[i])
+		if c < 0 {
+			return 0, fmt.Errorf("invalid character %q", src[i])
+		}
+		value |= uint32(c) << (6 * i)
+	}
+	return value, nil
+}
+
+// decode64Bytes inverts encode64Bytes. Each group of 4 characters carries 3
+// bytes; a trailing group of 2 or 3 characters carries 1 or 2 bytes.
+func decode64Bytes(src string) ([]byte, error) {
+	if len(src)%4 == 1 {
+		return nil, fmt.Errorf("invalid length %d", len(src))
+	}
+	result := make([]byte, 0, len(src)*3/4)
+	for i := 0; i < len(src); i += 4 {
+		group := src[i:min(i+4, len(src))]
+		var value uint32
+		for j := 0; j < len(group); j++ {
+			c := strings.IndexByte(itoa64, group[j])
+			if c < 0 {
+				return nil, fmt.Errorf("invalid character %q", group[j])
+			}
+			value |= uint32(c) << (6 * j)
+		}
+		for b := 0; b < len(group)*6/8; b++ {
+			result = append(result, byte(value>>(8*b)))
+		}
+	}
+	return result, nil
+}
+
+// The escrypt $7$ header: the prefix, log2(N) in 6 bits and r and p in 30
+// bits each, at 6 bits per character. The encoded salt follows it directly.
+const (
+	scryptPrefix    = "$7$"
+	scryptLogNBits  = 6
+	scryptRPBits    = 30
+	scryptHeaderLen = len(scryptPrefix) + scryptLogNBits/6 + 2*scryptRPBits/6
+)
+
+// Lengths of the derived key libsodium stores in a $7$ hash and of the raw
+// salt it generates.
+const (
+	scryptKeyLen  = 32
+	scryptSaltLen = 32
+)
+
+// parseSCryptParams decodes N, r and p from the header of an escrypt $7$
+// hash: "$7$" + log2(N) (1 char) + r (5 chars) + p (5 chars).
+func parseSCryptParams(hash string) (scryptParams, error) {
+	var params scryptParams
+	if !strings.HasPrefix(hash, scryptPrefix) {
+		return params, fmt.Errorf("not an SCrypt hash: must start with $7$")
+	}
+	if len(hash) < scryptHeaderLen {
+		return params, fmt.Errorf("header too short")
+	}
+
+	logNEnd := len(scryptPrefix) + scryptLogNBits/6
+	rEnd := logNEnd + scryptRPBits/6
+	var err error
+	if params.LogN, err = decode64Uint32(hash[len(scryptPrefix):logNEnd], scryptLogNBits); err != nil {
+		return params, fmt.Errorf("invalid N: %v", err)
+	}
+	if params.R, err = decode64Uint32(hash[logNEnd:rEnd], scryptRPBits); err != nil {
+		return params, fmt.Errorf("invalid r: %v", err)
+	}
+	if params.P, err = decode64Uint32(hash[rEnd:scryptHeaderLen], scryptRPBits); err != nil {
+		return params, fmt.Errorf("invalid p: %v", err)
+	}
+	if params.LogN < 1 || params.LogN > 63 {
+		return params, fmt.Errorf("invalid N: log2(N) = %d", params.LogN)
+	}
+	// scrypt divides by p, and r=0 is just as meaningless.
+	if params.R == 0 || params.P == 0 {
+		return params, fmt.Errorf("invalid r=%d, p=%d: both must be at least 1", params.R, params.P)
+	}
+	return params, nil
+}
+
+// parseSCryptMCF splits an escrypt hash into its $-separated fields,
+//
+//	"" / "7" / <N, r, p and salt> / <hash>
+//
+// and returns the parameters, the salt exactly as encoded (which is what
+// scrypt is given) and the decoded hash. Anything with extra sections, such
+// as the client/server keys some yescrypt-style strings append, is rejected
+// rather than guessing which part is the salt.
+func parseSCryptMCF(s string) (scryptParams, string, []byte, error) {
+	fields := strings.Split(s, "$")
+	if len(fields) < 2 || fields[0] != "" || fields[1] != "7" {
+		return scryptParams{}, "", nil, fmt.Errorf("not an SCrypt hash: must start with $7$")
+	}
+	if len(fields) > 4 {
+		// itoa64 has no $, so an extra one means the salt or hash is corrupted.
+		return scryptParams{}, "", nil, fmt.Errorf("expected 3 $-separated fields, found %d: the salt and hash can't contain $", len(fields)-1)
+	}
+	if len(fields) != 4 {
+		return scryptParams{}, "", nil, fmt.Errorf("expected 3 $-separated fields, found %d", len(fields)-1)
+	}
+
+	params, err := parseSCryptParams(scryptPrefix + fields[2])
+	if err != nil {
+		return params, "", nil, err
+	}
+	encodedSalt := fields[2][scryptHeaderLen-len(scryptPrefix):]
+	if encodedSalt == "" {
+		return params, "", nil, fmt.Errorf("missing salt")
+	}
+	// libsodium uses the salt characters as they are without decoding them,
+	// so only this check catches a salt mangled by a bad copy or charset
+	// conversion.
+	if i := strings.IndexFunc(encodedSalt, func(r rune) bool { return !strings.ContainsRune(itoa64, r) }); i >= 0 {
+		r, _ := utf8.DecodeRuneInString(encodedSalt[i:])
+		return params, "", nil, fmt.Errorf("invalid character %q at position %d of the salt (escrypt salts only use ./0-9A-Za-z)", r, i+1)
+	}
+	hash, err := decode64Bytes(fields[3])
+	if err != nil {
+		return params, "", nil, fmt.Errorf("invalid hash encoding: %v", err)
+	}
+	if len(hash) != scryptKeyLen {
+		return params, "", nil, fmt.Errorf("hash is %d bytes, expected %d", len(hash), scryptKeyLen)
+	}
+	return params, encodedSalt, hash, nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDecode64Uint32RoundTrip(t *testing.T) {
+	for _, bits := range []int{6, 30} {
+		for _, value := range []uint32{0, 1, 8, 14, 63, 64, 12345, 1<<30 - 1} {
+			if bits == 6 && value > 63 {
+				continue
+			}
+			got, err := decode64Uint32(encode64Uint32(value, bits), bits)
+			if err != nil || got != value {
+				t.Errorf("decode64Uint32(encode64Uint32(%d, %d)) = %d, %v", value, bits, got, err)
+			}
+		}
+	}
+	if _, err := decode64Uint32("6.$..", 30); err == nil {
+		t.Error("expected an error for a character outside the alphabet")
+	}
+}
+
+func TestParseSCryptParams(t *testing.T) {
+	params, err := parseSCryptParams(goldenVectors[14])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (scryptParams{LogN: 14, R: 8, P: 1}); params != want {
+		t.Errorf("params = %v, want %v", params, want)
+	}
+	if params.N() != 16384 {
+		t.Errorf("N = %d, want 16384", params.N())
+	}
+
+	for _, bad := range []string{"", "$7$C6...", "$argon2id$v=19$m=65536,t=2,p=1$", "$7$.6..../....abc", "$7$C6.........", "$7$C...../...."} {
+		if _, err := parseSCryptParams(bad); err == nil {
+			t.Errorf("parseSCryptParams(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestSCryptMemoryCap(t *testing.T) {
+	if got, want := scryptInteractive.memory(), uint64(16<<20); got != want {
+		t.Errorf("interactive memory = %d, want %d", got, want)
+	}
+	if err := scryptInteractive.checkMemory(); err != nil {
+		t.Errorf("interactive parameters refused: %v", err)
+	}
+
+	// N=2^30, r=8 would need 1 TiB.
+	huge := scryptParams{LogN: 30, R: 8, P: 1}
+	if _, err := hashSCryptWithParams(goldenPassword, "salt", huge); err == nil {
+		t.Error("expected 1 TiB of scrypt memory to be refused")
+	}
+	if got := (scryptParams{LogN: 63, R: 8, P: 1}).memory(); got != math.MaxUint64 {
+		t.Errorf("memory for N=2^63 = %d, want saturation", got)
+	}
+}
+
+func TestSCryptMinimum(t *testing.T) {
+	for _, weak := range []scryptParams{{LogN: 4, R: 1, P: 1}, {LogN: 10, R: 4, P: 1}, {LogN: 14, R: 8, P: 0}} {
+		if _, err := hashSCryptWithParams(goldenPassword, "salt", weak); err == nil || !strings.Contains(err.Error(), "minimum") {
+			t.Errorf("%v: got %v, want a minimum error", weak, err)
+		}
+	}
+	if err := scryptMinimum.checkMinimum(); err != nil {
+		t.Errorf("minimum refused: %v", err)
+	}
+	if !scryptMinimum.belowInteractive() || scryptInteractive.belowInteractive() {
+		t.Error("belowInteractive disagrees with the defaults")
+	}
+	if (scryptParams{LogN: 15, R: 8, P: 1}).belowInteractive() {
+		t.Error("N=2^15 reported below interactive")
+	}
+	// More parallel work doesn't make up for less memory.
+	if !(scryptParams{LogN: 13, R: 8, P: 4}).belowInteractive() {
+		t.Error("N=2^13, p=4 not reported below interactive")
+	}
+}
+
+func TestDecode64BytesRoundTrip(t *testing.T) {
+	src := make([]byte, 40)
+	for i := range src {
+		src[i] = byte(i*37 + 11)
+	}
+	for n := 0; n <= len(src); n++ {
+		encoded := encode64Bytes(src[:n])
+		got, err := decode64Bytes(encoded)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", n, err)
+		}
+		if !bytes.Equal(got, src[:n]) {
+			t.Errorf("%d bytes: round trip gave %x, want %x", n, got, src[:n])
+		}
+	}
+
+	for _, bad := range []string{"abcde", "ab!d"} {
+		if _, err := decode64Bytes(bad); err == nil {
+			t.Errorf("decode64Bytes(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestSCryptHeaderLen(t *testing.T) {
+	// "$7$" + 1 character for log2(N) + 5 each for r and p.
+	if scryptHeaderLen != 14 {
+		t.Errorf("scryptHeaderLen = %d, want 14", scryptHeaderLen)
+	}
+	for _, params := range []scryptParams{scryptInteractive, scryptMinimum, {LogN: 11, R: 16, P: 2}} {
+		hash, err := hashSCryptWithParams("password", "salt", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(hash[scryptHeaderLen:], "salt$") {
+			t.Errorf("%v: the salt doesn't start at offset %d of %q", params, scryptHeaderLen, hash)
+		}
+		got, salt, _, err := parseSCryptMCF(hash)
+		if err != nil || got != params || salt != "salt" {
+			t.Errorf("%v: parsed %v, salt %q, err %v", params, got, salt, err)
+		}
+	}
+}
+
+func TestParseSCryptMCF(t *testing.T) {
+	params, salt, hash, err := parseSCryptMCF(goldenVectors[14])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params != scryptInteractive || !strings.HasPrefix(goldenVectors[14][scryptHeaderLen:], salt+"$") || len(hash) != scryptKeyLen {
+		t.Errorf("got %v, salt %q, %d-byte hash", params, salt, len(hash))
+	}
+
+	for _, bad := range []string{
+		// Trailing client/server key section.
+		goldenVectors[14] + "$c2VydmVyS2V5",
+		"$7$C6..../....salt",
+		"$7$C6..../....$" + goldenVectors[14][strings.LastIndex(goldenVectors[14], "$")+1:],
+		"$7$C6..../....salt$short",
+		"$8$C6..../....salt$hash",
+	} {
+		if _, _, _, err := parseSCryptMCF(bad); err == nil {
+			t.Errorf("parseSCryptMCF(%q): expected an error", bad)
+		}
+		if ok, err := verifySCrypt(bad, goldenPassword); err == nil || ok || errors.Is(err, errSCryptDerive) {
+			t.Errorf("verifySCrypt(%q) = %v, %v, want a malformed hash error", bad, ok, err)
+		}
+	}
+
+	// Well-formed, but N=2^30 is over the memory cap.
+	huge := "$7$S" + goldenVectors[14][4:]
+	if ok, err := verifySCrypt(huge, goldenPassword); ok || !errors.Is(err, errSCryptDerive) {
+		t.Errorf("verifySCrypt(N=2^30) = %v, %v, want errSCryptDerive", ok, err)
+	}
+	if ok, err := verifySCrypt(goldenVectors[14], "wrong"); ok || err != nil {
+		t.Errorf("wrong password: %v, %v, want a plain mismatch", ok, err)
+	}
+
+	// Corrupted salts: a $ splits the salt, anything else outside itoa64 is
+	// named with its position.
+	golden := goldenVectors[14]
+	for _, tt := range []struct{ hash, err string }{
+		{golden[:20] + "$" + golden[21:], "can't contain $"},
+		{golden[:20] + "+" + golden[21:], `invalid character '+' at position 7 of the salt`},
+		{golden[:14] + "é" + golden[16:], `invalid character 'é' at position 1 of the salt`},
+		{golden[:30] + "\x00" + golden[31:], `invalid character '\x00' at position 17`},
+	} {
+		if _, _, _, err := parseSCryptMCF(tt.hash); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("parseSCryptMCF(%q): got %v, want %q", tt.hash, err, tt.err)
+		}
+	}
+}
+
+// FuzzVerifySCrypt feeds verifySCrypt arbitrary stored hashes, such as
+// corrupted database values, which must be rejected without a panic.
+func FuzzVerifySCrypt(f *testing.F) {
+	golden := goldenVectors[14]
+	for _, seed := range []string{
+		golden, "", "$", "$7$", "$7$$", "$7$C6..../....", "$7$C6..../....$", golden + "$", golden[:14],
+		golden[:len(golden)-1], strings.Replace(golden, "$", "", 2), "$7$\xff\xfe", goldenVectors[13],
+	} {
+		f.Add(seed)
+	}
+	// Deriving is skipped for anything costlier than the cheapest hashes we
+	// generate, or the fuzzer spends its time (and memory) inside scrypt.
+	f.Fuzz(func(t *testing.T, hash string) {
+		if params, _, _, err := parseSCryptMCF(hash); err == nil && (params.memory() > scryptMinimum.memory() || params.P > scryptMinimum.P) {
+			t.Skip()
+		}
+		ok, err := verifySCrypt(hash, goldenPassword)
+		if ok && err != nil {
+			t.Errorf("%q: matched with error %v", hash, err)
+		}
+		if ok && hash != golden {
+			t.Errorf("%q matched the golden password", hash)
+		}
+	})
+}
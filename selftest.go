@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Fixed inputs for the self-test. The username contains no ':' so every
+// mode's input is unambiguous.
+const (
+	selfTestUsername = "selftest"
+	selfTestPassword = "Self-Test#123"
+)
+
+// selfTestMode hashes the self-test password with mode and checks that the
+// result is recognized as that mode, verifies, and rejects a wrong password.
+// It catches a crypto dependency update that breaks hashing and verification
+// in different ways.
+func selfTestMode(mode int) error {
+	hash, err := eqcryptHash(selfTestUsername, selfTestPassword, mode)
+	if err != nil {
+		return fmt.Errorf("hashing: %w", err)
+	}
+	if !slices.Contains(detectHashModes(hash), mode) {
+		return fmt.Errorf("hash %q is not recognized as mode %d", hash, mode)
+	}
+	ok, err := verifyHash(hash, selfTestUsername, selfTestPassword, mode)
+	if err != nil {
+		return fmt.Errorf("verifying: %w", err)
+	}
+	if !ok {
+		return errors.New("hash does not verify against its own password")
+	}
+	if ok, _ := verifyHash(hash, selfTestUsername, selfTestPassword+"x", mode); ok {
+		return errors.New("hash verifies against the wrong password")
+	}
+	return nil
+}
+
+// runSelfTest round-trips every mode, writing one line per mode to w, and
+// returns the number of modes that failed.
+func runSelfTest(w io.Writer) int {
+	failed := 0
+	for _, m := range modeTable {
+		if err := selfTestMode(m.Number); err != nil {
+			failed++
+			fmt.Fprintf(w, "FAIL  %2d  %s: %v\n", m.Number, m.Label, err)
+		} else {
+			fmt.Fprintf(w, "ok    %2d  %s\n", m.Number, m.Label)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	var out bytes.Buffer
+	if failed := runSelfTest(&out); failed != 0 {
+		t.Fatalf("%d modes failed:\n%s", failed, out.String())
+	}
+	if lines := strings.Count(out.String(), "\n"); lines != len(modeOptions) {
+		t.Errorf("got %d lines, want one per mode:\n%s", lines, out.String())
+	}
+
+	var stdout, stderr bytes.Buffer
+	if !cliRequested([]string{"-selftest"}) {
+		t.Error("-selftest not detected as a CLI launch")
+	}
+	if code := runCLI([]string{"-selftest"}, strings.NewReader(""), &stdout, &stderr); code != exitOK {
+		t.Errorf("exit code %d, stderr: %s", code, stderr.String())
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// envServeToken holds the -serve token when -serve-token is absent, which
+// keeps it out of the process list.
+const envServeToken = "EQHASH_SERVE_TOKEN"
+
+// Largest request body the server reads; requests only carry a hash, an
+// account name and a password.
+const serveMaxBody = 64 << 10
+
+// Limits on the cost of a hash POST /verify will derive. The parameters come
+// from the client, so without them one request could ask for gigabytes of
+// memory or minutes of CPU. libsodium's MODERATE presets fit; SENSITIVE
+// hashes have to be checked with -verify instead.
+const (
+	serveMaxArgon2Time = 4
+	serveMaxSCryptP    = 16
+	// Memory times Argon2 passes, or SCrypt memory times p.
+	serveMaxWorkMiB = 1024
+)
+
+// serveMaxDerivations bounds the hashes the server derives at once, so
+// parallel requests queue instead of exhausting memory.
+var serveMaxDerivations = runtime.NumCPU()
+
+// checkServeCost refuses an Argon2 or SCrypt hash whose parameters exceed
+// the serve limits. A malformed hash is left for the verifier to report.
+func checkServeCost(hash string) error {
+	switch {
+	case strings.HasPrefix(hash, "$argon2"):
+		params, _, _, err := parseArgon2PHC(hash)
+		if err != nil {
+			return nil
+		}
+		if params.Time > serveMaxArgon2Time {
+			return fmt.Errorf("Argon2 t=%d is over the server's limit of %d", params.Time, serveMaxArgon2Time)
+		}
+		if work := uint64(params.Memory) * uint64(params.Time) >> 10; work > serveMaxWorkMiB {
+			return fmt.Errorf("Argon2 %v is over the server's limit of %d MiB times passes", params, serveMaxWorkMiB)
+		}
+	case strings.HasPrefix(hash, scryptPrefix):
+		params, _, _, err := parseSCryptMCF(hash)
+		if err != nil {
+			return nil
+		}
+		if params.P > serveMaxSCryptP {
+			return fmt.Errorf("SCrypt p=%d is over the server's limit of %d", params.P, serveMaxSCryptP)
+		}
+		if mem := params.memory() >> 20; mem > serveMaxWorkMiB || mem*uint64(params.P) > serveMaxWorkMiB {
+			return fmt.Errorf("SCrypt %v is over the server's limit of %d MiB times p", params, serveMaxWorkMiB)
+		}
+	}
+	return nil
+}
+
+// serveRequest is the body of POST /hash and POST /verify. Mode is required
+// for /hash; for /verify it works like -verify's -mode, and without it every
+// mode that fits the hash is tried.
+type serveRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Mode     *int   `json:"mode"`
+	Hash     string `json:"hash"`
+}
+
+// serveVerifyResult is the response of POST /verify. MatchedMode is the mode
+// the password matched under, which with a mode given may not be that one.
+type serveVerifyResult struct {
+	Match       bool `json:"match"`
+	MatchedMode int  `json:"matched_mode,omitempty"`
+}
+
+// serveAddr binds an address given as just ":port" to localhost, so the
+// server is only reachable from other machines when a host is named.
+func serveAddr(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "127.0.0.1" + addr
+	}
+	return addr
+}
+
+// newServeHandler returns the -serve API. Every request needs an
+// "Authorization: Bearer <token>" header.
+func newServeHandler(token string, logw io.Writer) http.Handler {
+	// derive runs one hash under the derivation limit, giving up when the
+	// client goes away. A derivation already started can't be interrupted,
+	// so its slot is only freed once it really finishes.
+	slots := make(chan struct{}, serveMaxDerivations)
+	derive := func(ctx context.Context, hash func() (string, error)) (string, error) {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		return withContext(ctx, func() (string, error) {
+			defer func() { <-slots }()
+			return hash()
+		})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hash", func(w http.ResponseWriter, r *http.Request) {
+		req, ok := readServeRequest(w, r)
+		if !ok {
+			return
+		}
+		if req.Mode == nil {
+			writeServeError(w, http.StatusBadRequest, "mode is required")
+			return
+		}
+		if req.Password == "" {
+			writeServeError(w, http.StatusBadRequest, "password is required")
+			return
+		}
+		if modeNeedsUsername[*req.Mode] && req.Username == "" {
+			writeServeError(w, http.StatusBadRequest, fmt.Sprintf("mode %d requires username", *req.Mode))
+			return
+		}
+		hash, err := derive(r.Context(), func() (string, error) {
+			return eqcryptHash(req.Username, req.Password, *req.Mode)
+		})
+		if err != nil {
+			writeServeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeServeJSON(w, http.StatusOK, cliResult{Mode: *req.Mode, Username: req.Username, Hash: outputHash(hash)})
+	})
+	mux.HandleFunc("/verify", func(w http.ResponseWriter, r *http.Request) {
+		req, ok := readServeRequest(w, r)
+		if !ok {
+			return
+		}
+		hash, _ := cleanHash(req.Hash)
+		if hash == "" || req.Password == "" {
+			writeServeError(w, http.StatusBadRequest, "hash and password are required")
+			return
+		}
+		selected := 0
+		if req.Mode != nil {
+			if _, ok := lookupMode(*req.Mode); !ok {
+				writeServeError(w, http.StatusBadRequest, fmt.Sprintf("unsupported encryption mode: %d", *req.Mode))
+				return
+			}
+			selected = *req.Mode
+		}
+		if err := checkServeCost(hash); err != nil {
+			writeServeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		var matched int
+		_, err := derive(r.Context(), func() (string, error) {
+			var err error
+			matched, err = verifySelected(hash, req.Username, req.Password, selected)
+			return "", err
+		})
+		if err != nil {
+			writeServeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeServeJSON(w, http.StatusOK, serveVerifyResult{
+			Match:       matched != 0 && (selected == 0 || matched == selected),
+			MatchedMode: matched,
+		})
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			// Never the body: it holds the password.
+			fmt.Fprintf(logw, "%s %s %s %d\n", time.Now().Format(time.RFC3339), r.Method, r.URL.Path, rec.status)
+		}()
+		defer recoverPanic(func(err error) {
+			writeServeError(rec, http.StatusInternalServerError, err.Error())
+		})
+
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			writeServeError(rec, http.StatusUnauthorized, "missing or wrong token")
+			return
+		}
+		mux.ServeHTTP(rec, r)
+	})
+}
+
+// readServeRequest decodes a POST body, answering the request itself if it
+// can't.
+func readServeRequest(w http.ResponseWriter, r *http.Request) (serveRequest, bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeServeError(w, http.StatusMethodNotAllowed, "use POST")
+		return serveRequest{}, false
+	}
+	var req serveRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, serveMaxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeServeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		} else {
+			writeServeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		}
+		return serveRequest{}, false
+	}
+	return req, true
+}
+
+func writeServeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeServeError(w http.ResponseWriter, status int, msg string) {
+	writeServeJSON(w, status, cliError{Error: msg})
+}
+
+// statusRecorder keeps the response status for the request log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// runServe implements -serve: it listens on addr until interrupted or
+// terminated.
+func runServe(addr, token string, stderr io.Writer) int {
+	if token == "" {
+		fmt.Fprintf(stderr, "error: -serve requires a token (-serve-token or %s)\n", envServeToken)
+		return exitUsage
+	}
+	ln, err := net.Listen("tcp", serveAddr(addr))
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitUsage
+	}
+	server := &http.Server{
+		Handler:           newServeHandler(token, stderr),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// SIGTERM is what systemd and docker stop send.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(stderr, "serving on http://%s (POST /hash, POST /verify)\n", ln.Addr())
+	if err := server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitError
+	}
+	return exitOK
+}
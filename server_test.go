@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testServeToken = "s3cret"
+
+func serveCall(t *testing.T, h http.Handler, method, path, token, body string) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var out map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("%s %s: response is not JSON: %q", method, path, rec.Body.String())
+	}
+	return rec.Code, out
+}
+
+func TestServeHash(t *testing.T) {
+	var log bytes.Buffer
+	h := newServeHandler(testServeToken, &log)
+
+	code, out := serveCall(t, h, "POST", "/hash", testServeToken,
+		`{"username":"`+goldenUsername+`","password":"`+goldenPassword+`","mode":2}`)
+	if code != http.StatusOK || out["hash"] != goldenVectors[2] {
+		t.Errorf("mode 2: %d %v", code, out)
+	}
+	if strings.Contains(log.String(), goldenPassword) {
+		t.Errorf("password logged: %s", log.String())
+	}
+
+	for _, tt := range []struct {
+		method, token, body string
+		want                int
+	}{
+		{"POST", "", `{"password":"x","mode":1}`, http.StatusUnauthorized},
+		{"POST", "wrong", `{"password":"x","mode":1}`, http.StatusUnauthorized},
+		{"GET", testServeToken, ``, http.StatusMethodNotAllowed},
+		{"POST", testServeToken, `{"password":"x"}`, http.StatusBadRequest},
+		{"POST", testServeToken, `{"password":"x","mode":99}`, http.StatusBadRequest},
+		{"POST", testServeToken, `{"password":"x","mode":2}`, http.StatusBadRequest},
+		{"POST", testServeToken, `{"password":"x","mode":1,"extra":1}`, http.StatusBadRequest},
+		{"POST", testServeToken, `{"password":"` + strings.Repeat("x", serveMaxBody) + `","mode":1}`, http.StatusRequestEntityTooLarge},
+	} {
+		if code, out := serveCall(t, h, tt.method, "/hash", tt.token, tt.body); code != tt.want || out["error"] == nil {
+			t.Errorf("%s %.40s (token %q): %d %v, want %d and an error", tt.method, tt.body, tt.token, code, out, tt.want)
+		}
+	}
+}
+
+func TestServeRequiresBearer(t *testing.T) {
+	h := newServeHandler(testServeToken, io.Discard)
+	for _, header := range []string{testServeToken, "bearer " + testServeToken, "Basic " + testServeToken, "Bearer"} {
+		req := httptest.NewRequest("POST", "/hash", strings.NewReader(`{"password":"x","mode":1}`))
+		req.Header.Set("Authorization", header)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: %d, want %d", header, rec.Code, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestServeVerify(t *testing.T) {
+	h := newServeHandler(testServeToken, io.Discard)
+
+	tests := []struct {
+		body    string
+		match   bool
+		matched float64
+	}{
+		{`{"hash":"` + goldenVectors[14] + `","password":"` + goldenPassword + `"}`, true, 14},
+		{`{"hash":"` + goldenVectors[14] + `","password":"wrong"}`, false, 0},
+		{`{"hash":"` + goldenVectors[6] + `","username":"` + goldenUsername + `","password":"` + goldenPassword + `","mode":6}`, true, 6},
+		// The password matches, but under mode 6 rather than the one given.
+		{`{"hash":"` + goldenVectors[6] + `","username":"` + goldenUsername + `","password":"` + goldenPassword + `","mode":7}`, false, 6},
+	}
+	for _, tt := range tests {
+		code, out := serveCall(t, h, "POST", "/verify", testServeToken, tt.body)
+		matched, _ := out["matched_mode"].(float64)
+		if code != http.StatusOK || out["match"] != tt.match || matched != tt.matched {
+			t.Errorf("%.60s: %d %v", tt.body, code, out)
+		}
+	}
+
+	if code, _ := serveCall(t, h, "POST", "/verify", testServeToken, `{"password":"x"}`); code != http.StatusBadRequest {
+		t.Errorf("no hash: %d", code)
+	}
+	if code, _ := serveCall(t, h, "POST", "/verify", testServeToken, `{"hash":"zz","password":"x"}`); code != http.StatusBadRequest {
+		t.Errorf("unrecognized hash: %d", code)
+	}
+}
+
+func TestCheckServeCost(t *testing.T) {
+	fields := strings.Split(goldenVectors[13], "$")
+	argon2Hash := func(params string) string { return "$argon2id$v=19$" + params + "$" + fields[4] + "$" + fields[5] }
+	scryptHash := func(p scryptParams) string {
+		return scryptPrefix + encode64Uint32(p.LogN, scryptLogNBits) + encode64Uint32(p.R, scryptRPBits) +
+			encode64Uint32(p.P, scryptRPBits) + "salt$" + encode64Bytes(make([]byte, scryptKeyLen))
+	}
+
+	for _, hash := range []string{
+		goldenVectors[13], goldenVectors[14], goldenVectors[1],
+		argon2Hash("m=262144,t=3,p=1"),                  // MODERATE
+		scryptHash(scryptParams{LogN: 16, R: 8, P: 16}), // 64 MiB times 16
+		"$argon2id$garbage",
+	} {
+		if err := checkServeCost(hash); err != nil {
+			t.Errorf("%.50s: %v", hash, err)
+		}
+	}
+	for _, hash := range []string{
+		argon2Hash("m=8,t=100,p=1"),
+		argon2Hash("m=1048576,t=4,p=1"), // SENSITIVE
+		argon2Hash("m=4194304,t=1,p=1"),
+		scryptHash(scryptParams{LogN: 10, R: 8, P: 1 << 20}),
+		scryptHash(scryptParams{LogN: 20, R: 8, P: 2}),
+		scryptHash(scryptParams{LogN: 24, R: 8, P: 1}),
+	} {
+		if err := checkServeCost(hash); err == nil {
+			t.Errorf("%.50s: expected an error", hash)
+		}
+	}
+
+	h := newServeHandler(testServeToken, io.Discard)
+	code, out := serveCall(t, h, "POST", "/verify", testServeToken,
+		`{"hash":"`+argon2Hash("m=4194304,t=1,p=1")+`","password":"x"}`)
+	if code != http.StatusBadRequest || !strings.Contains(fmt.Sprint(out["error"]), "limit") {
+		t.Errorf("expensive hash: %d %v", code, out)
+	}
+}
+
+func TestServeVerifyCancelled(t *testing.T) {
+	h := newServeHandler(testServeToken, io.Discard)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("POST", "/verify", strings.NewReader(`{"hash":"`+goldenVectors[14]+`","password":"x"}`)).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+testServeToken)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code == http.StatusOK || !strings.Contains(rec.Body.String(), context.Canceled.Error()) {
+		t.Errorf("cancelled request: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestServeAddr(t *testing.T) {
+	for in, want := range map[string]string{":8080": "127.0.0.1:8080", "0.0.0.0:8080": "0.0.0.0:8080", "[::1]:80": "[::1]:80"} {
+		if got := serveAddr(in); got != want {
+			t.Errorf("serveAddr(%q) = %q, want %q", in, got, want)
+		}
+	}
+	var stderr bytes.Buffer
+	if code := runServe(":0", "", &stderr); code != exitUsage {
+		t.Errorf("no token: exit code %d", code)
+	}
+}
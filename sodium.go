@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math"
+)
+
+// sodiumLimits is a libsodium crypto_pwhash cost pair. What the numbers mean
+// depends on the algorithm: for Argon2id OpsLimit is the number of passes,
+// for scrypt it is a rough count of operations that pickparams turns into
+// N, r and p. MemLimit is in bytes for both.
+type sodiumLimits struct {
+	OpsLimit uint64
+	MemLimit uint64
+}
+
+// Names of libsodium's cost presets, e.g. crypto_pwhash_OPSLIMIT_MODERATE.
+var sodiumPresetNames = []string{"INTERACTIVE", "MODERATE", "SENSITIVE"}
+
+// crypto_pwhash_argon2id_{OPS,MEM}LIMIT_* and
+// crypto_pwhash_scryptsalsa208sha256_{OPS,MEM}LIMIT_*. libsodium has no
+// MODERATE preset for scrypt.
+var sodiumPresets = map[int]map[string]sodiumLimits{
+	13: {
+		"INTERACTIVE": {2, 67108864},
+		"MODERATE":    {3, 268435456},
+		"SENSITIVE":   {4, 1073741824},
+	},
+	14: {
+		"INTERACTIVE": {524288, 16777216},
+		"SENSITIVE":   {33554432, 1073741824},
+	},
+}
+
+// sodiumPreset returns the named preset for mode 13 or 14.
+func sodiumPreset(mode int, name string) (sodiumLimits, error) {
+	limits, ok := sodiumPresets[mode][name]
+	if !ok {
+		return sodiumLimits{}, fmt.Errorf("libsodium has no %s preset for %s", name, modeName(mode))
+	}
+	return limits, nil
+}
+
+// argon2FromSodium converts limits to Argon2 parameters the way
+// crypto_pwhash_argon2id_str does: t = opslimit, m = memlimit / 1024 KiB,
+// and always one lane.
+func argon2FromSodium(limits sodiumLimits) (argon2Params, error) {
+	const opsLimitMin, memLimitMin = 1, 8192 // crypto_pwhash_argon2id_*_MIN
+	switch {
+	case limits.OpsLimit < opsLimitMin || limits.OpsLimit > math.MaxUint32:
+		return argon2Params{}, fmt.Errorf("Argon2 OPSLIMIT %d must be at least %d and at most %d", limits.OpsLimit, opsLimitMin, uint32(math.MaxUint32))
+	case limits.MemLimit < memLimitMin:
+		return argon2Params{}, fmt.Errorf("Argon2 MEMLIMIT %d is below the minimum of %d bytes", limits.MemLimit, memLimitMin)
+	case limits.MemLimit/1024 > argon2MaxMemoryKiB:
+		// Also catches anything too large for the uint32 m parameter.
+		return argon2Params{}, fmt.Errorf("Argon2 MEMLIMIT %d is over the maximum of %d bytes (m=%d KiB)", limits.MemLimit, uint64(argon2MaxMemoryKiB)*1024, argon2MaxMemoryKiB)
+	}
+	return argon2Params{Memory: uint32(limits.MemLimit / 1024), Time: uint32(limits.OpsLimit), Threads: 1}, nil
+}
+
+// scryptFromSodium converts limits to scrypt parameters exactly as
+// libsodium's pickparams in pwhash_scryptsalsa208sha256.c does, so
+// INTERACTIVE gives N=2^14, r=8, p=1 and SENSITIVE N=2^20, r=8, p=1.
+func scryptFromSodium(limits sodiumLimits) (scryptParams, error) {
+	// crypto_pwhash_scryptsalsa208sha256_{OPS,MEM}LIMIT_MIN. pickparams
+	// quietly raises a lower OPSLIMIT, but a typo shouldn't silently give
+	// different parameters than asked for.
+	const opsLimitMin, memLimitMin = 32768, 16777216
+	opsLimit, memLimit := limits.OpsLimit, limits.MemLimit
+	switch {
+	case opsLimit < opsLimitMin:
+		return scryptParams{}, fmt.Errorf("SCrypt OPSLIMIT %d is below the minimum of %d", opsLimit, opsLimitMin)
+	case memLimit < memLimitMin:
+		return scryptParams{}, fmt.Errorf("SCrypt MEMLIMIT %d is below the minimum of %d bytes", memLimit, memLimitMin)
+	}
+	params := scryptParams{R: 8}
+
+	// The smallest log2(N) with 2^log2(N) > maxN/2, capped at 63.
+	logN := func(maxN uint64) uint32 {
+		n := uint32(1)
+		for ; n < 63; n++ {
+			if uint64(1)<<n > maxN/2 {
+				break
+			}
+		}
+		return n
+	}
+
+	if opsLimit < memLimit/32 {
+		params.P = 1
+		params.LogN = logN(opsLimit / (uint64(params.R) * 4))
+	} else {
+		params.LogN = logN(memLimit / (uint64(params.R) * 128))
+		maxRP := (opsLimit / 4) / (uint64(1) << params.LogN)
+		if maxRP > 0x3fffffff {
+			maxRP = 0x3fffffff
+		}
+		params.P = uint32(maxRP) / params.R
+	}
+	if params.P == 0 {
+		return params, fmt.Errorf("SCrypt OPSLIMIT %d is too small for MEMLIMIT %d (p would be 0)", limits.OpsLimit, limits.MemLimit)
+	}
+	return params, nil
+}
+
+// describeSodiumLimits shows the parameters limits convert to for mode.
+func describeSodiumLimits(mode int, limits sodiumLimits) (string, error) {
+	if mode == 13 {
+		params, err := argon2FromSodium(limits)
+		if err != nil {
+			return "", err
+		}
+		return params.String(), nil
+	}
+	params, err := scryptFromSodium(limits)
+	if err != nil {
+		return "", err
+	}
+	if err := params.checkMemory(); err != nil {
+		return "", err
+	}
+	return params.String(), nil
+}
+
+// sodiumCostNote is kdfCostNote for the parameters limits convert to, or the
+// conversion error.
+func sodiumCostNote(mode int, limits sodiumLimits) string {
+	if mode == 13 {
+		params, err := argon2FromSodium(limits)
+		if err != nil {
+			return err.Error()
+		}
+		return kdfCostNote(mode, params, scryptParams{})
+	}
+	params, err := scryptFromSodium(limits)
+	if err != nil {
+		return err.Error()
+	}
+	return kdfCostNote(mode, argon2Params{}, params)
+}
+
+// hashWithSodiumLimits hashes password with mode 13 or 14 and a random salt,
+// using the parameters libsodium derives from limits instead of the
+// loginserver's INTERACTIVE defaults.
+func hashWithSodiumLimits(mode int, password string, limits sodiumLimits) (string, error) {
+	if password == "" {
+		return "", errEmptyPassword
+	}
+	if err := checkPasswordLength(password); err != nil {
+		return "", err
+	}
+
+	var hash string
+	switch mode {
+	case 13:
+		params, err := argon2FromSodium(limits)
+		if err != nil {
+			return "", err
+		}
+		saltLen, _ := argon2Settings()
+		if err := checkArgon2SaltLen(saltLen); err != nil {
+			return "", err
+		}
+		salt := make([]byte, saltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		if hash, err = hashArgon2WithParams(password, salt, params, argon2KeyLen); err != nil {
+			return "", err
+		}
+	case 14:
+		params, err := scryptFromSodium(limits)
+		if err != nil {
+			return "", err
+		}
+		salt := make([]byte, scryptSaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		if hash, err = hashSCryptWithParams(password, encode64Bytes(salt), params); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("libsodium limits only apply to modes 13 and 14, not %d", mode)
+	}
+	if err := checkHashFormat(mode, hash); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
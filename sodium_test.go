@@ -0,0 +1,138 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSodiumPresets(t *testing.T) {
+	limits, err := sodiumPreset(13, "INTERACTIVE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params, err := argon2FromSodium(limits); err != nil || params != argon2Interactive {
+		t.Errorf("Argon2 INTERACTIVE = %v, %v, want %v", params, err, argon2Interactive)
+	}
+
+	limits, err = sodiumPreset(14, "INTERACTIVE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params, err := scryptFromSodium(limits); err != nil || params != scryptInteractive {
+		t.Errorf("SCrypt INTERACTIVE = %v, %v, want %v", params, err, scryptInteractive)
+	}
+
+	limits, _ = sodiumPreset(14, "SENSITIVE")
+	if params, err := scryptFromSodium(limits); err != nil || params != (scryptParams{LogN: 20, R: 8, P: 1}) {
+		t.Errorf("SCrypt SENSITIVE = %v, %v", params, err)
+	}
+	limits, _ = sodiumPreset(13, "SENSITIVE")
+	if params, err := argon2FromSodium(limits); err != nil || params != (argon2Params{Memory: 1048576, Time: 4, Threads: 1}) {
+		t.Errorf("Argon2 SENSITIVE = %v, %v", params, err)
+	}
+
+	if _, err := sodiumPreset(14, "MODERATE"); err == nil {
+		t.Error("expected no MODERATE preset for SCrypt")
+	}
+}
+
+func TestSCryptFromSodium(t *testing.T) {
+	tests := []struct {
+		limits sodiumLimits
+		want   scryptParams
+	}{
+		// opslimit < memlimit/32: N from opslimit, p = 1
+		{sodiumLimits{65536, 67108864}, scryptParams{LogN: 11, R: 8, P: 1}},
+		// the minimums give libsodium's smallest parameters
+		{sodiumLimits{32768, 16777216}, scryptParams{LogN: 10, R: 8, P: 1}},
+		// otherwise N from memlimit and p from what opslimit has left
+		{sodiumLimits{4194304, 16777216}, scryptParams{LogN: 14, R: 8, P: 8}},
+	}
+	for _, tt := range tests {
+		if got, err := scryptFromSodium(tt.limits); err != nil || got != tt.want {
+			t.Errorf("%+v: got %v, %v, want %v", tt.limits, got, err, tt.want)
+		}
+	}
+
+	// libsodium would raise the OPSLIMIT, but it is refused so a typo
+	// doesn't quietly give other parameters.
+	for _, bad := range []sodiumLimits{{1, 16777216}, {524288, 8388608}} {
+		if _, err := scryptFromSodium(bad); err == nil || !strings.Contains(err.Error(), "minimum") {
+			t.Errorf("%+v: got %v, want a minimum error", bad, err)
+		}
+	}
+}
+
+func TestArgon2FromSodiumLimits(t *testing.T) {
+	for _, bad := range []sodiumLimits{{0, 67108864}, {2, 4096}, {2, (argon2MaxMemoryKiB + 1) * 1024}} {
+		if _, err := argon2FromSodium(bad); err == nil {
+			t.Errorf("%+v: expected an error", bad)
+		}
+	}
+	for _, huge := range []sodiumLimits{{2, (argon2MaxMemoryKiB + 1) * 1024}, {2, math.MaxUint64}} {
+		if _, err := argon2FromSodium(huge); err == nil || !strings.Contains(err.Error(), "maximum") {
+			t.Errorf("%+v: got %v, want a maximum error", huge, err)
+		}
+	}
+}
+
+func TestHashWithSodiumLimits(t *testing.T) {
+	hash, err := hashWithSodiumLimits(13, goldenPassword, sodiumLimits{1, 65536})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params, _, _, err := parseArgon2PHC(hash); err != nil || params != (argon2Params{Memory: 64, Time: 1, Threads: 1}) {
+		t.Errorf("Argon2 hash %q has parameters %v, %v", hash, params, err)
+	}
+	if ok, err := verifyArgon2(hash, goldenPassword); !ok || err != nil {
+		t.Errorf("Argon2 hash does not verify: %v", err)
+	}
+
+	hash, err = hashWithSodiumLimits(14, goldenPassword, sodiumLimits{32768, 16777216})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params, _, _, err := parseSCryptMCF(hash); err != nil || params != (scryptParams{LogN: 10, R: 8, P: 1}) {
+		t.Errorf("SCrypt hash %q has parameters %v, %v", hash, params, err)
+	}
+	if ok, err := verifySCrypt(hash, goldenPassword); !ok || err != nil {
+		t.Errorf("SCrypt hash does not verify: %v", err)
+	}
+
+	if _, err := hashWithSodiumLimits(5, goldenPassword, sodiumLimits{2, 67108864}); err == nil {
+		t.Error("expected an error for a non-KDF mode")
+	}
+
+	// The Argon2 salt follows the salt length setting.
+	defer func(old int) { argon2SaltLen = old }(argon2SaltLen)
+	argon2SaltLen = 24
+	hash, err = hashWithSodiumLimits(13, goldenPassword, sodiumLimits{1, 65536})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, salt, _, err := parseArgon2PHC(hash); err != nil || len(salt) != 24 {
+		t.Errorf("Argon2 hash %q has a %d-byte salt, want 24 (%v)", hash, len(salt), err)
+	}
+	argon2SaltLen = argon2MinSaltLen - 1
+	if _, err := hashWithSodiumLimits(13, goldenPassword, sodiumLimits{1, 65536}); err == nil {
+		t.Error("expected an error for a salt length below the minimum")
+	}
+}
+
+func TestSodiumCostNote(t *testing.T) {
+	for _, name := range []string{"INTERACTIVE", "SENSITIVE"} {
+		for _, mode := range []int{13, 14} {
+			limits, _ := sodiumPreset(mode, name)
+			if note := sodiumCostNote(mode, limits); note != "" {
+				t.Errorf("mode %d %s: unexpected note %q", mode, name, note)
+			}
+		}
+	}
+	if note := sodiumCostNote(13, sodiumLimits{1, 8192}); !strings.Contains(note, "m=65536, t=2, p=1") {
+		t.Errorf("Argon2 m=8, t=1: note %q does not name the default", note)
+	}
+	if note := sodiumCostNote(14, sodiumLimits{1, 16777216}); !strings.Contains(note, "minimum of 32768") {
+		t.Errorf("SCrypt OPSLIMIT 1: note %q", note)
+	}
+}
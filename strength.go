@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Message IDs of the labels for the scores returned by scorePassword,
+// weakest first.
+var strengthLabels = []string{"strength.very_weak", "strength.weak", "strength.fair", "strength.strong", "strength.very_strong"}
+
+// A handful of passwords that show up at the top of every breach list.
+// Anything in here scores 0 regardless of length or character classes.
+var commonPasswords = map[string]bool{
+	"123456": true, "12345678": true, "123456789": true, "1234567890": true,
+	"password": true, "password1": true, "password123": true, "passw0rd": true,
+	"qwerty": true, "qwerty123": true, "qwertyuiop": true, "abc123": true,
+	"111111": true, "000000": true, "letmein": true, "welcome": true,
+	"iloveyou": true, "admin": true, "admin123": true, "monkey": true,
+	"dragon": true, "master": true, "sunshine": true, "princess": true,
+	"football": true, "baseball": true, "trustno1": true, "changeme": true,
+	"everquest": true, "norrath": true, "eqemu": true, "test123": true,
+}
+
+// scorePassword estimates password strength on a 0-4 scale from the length,
+// the character classes used and a small common-password blocklist. It's a
+// rough heuristic meant to catch trivial account passwords, not a cracker.
+func scorePassword(password string) int {
+	if password == "" || commonPasswords[strings.ToLower(password)] {
+		return 0
+	}
+
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	unique := make(map[rune]bool)
+	length := 0
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+		unique[r] = true
+		length++
+	}
+
+	pool := 0
+	if hasLower {
+		pool += 26
+	}
+	if hasUpper {
+		pool += 26
+	}
+	if hasDigit {
+		pool += 10
+	}
+	if hasSymbol {
+		pool += 33
+	}
+
+	// Repeated characters add little, so "aaaaaaaaaaaa" shouldn't score like
+	// twelve random letters.
+	if length > 2*len(unique) {
+		length = 2 * len(unique)
+	}
+
+	bits := float64(length) * math.Log2(float64(pool))
+	switch {
+	case bits < 28:
+		return 0
+	case bits < 36:
+		return 1
+	case bits < 60:
+		return 2
+	case bits < 80:
+		return 3
+	default:
+		return 4
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestScorePassword(t *testing.T) {
+	tests := []struct {
+		password string
+		want     int
+	}{
+		{"", 0},
+
+		// Lowercase only, about 4.7 bits a character: the boundaries at
+		// 28, 36, 60 and 80 bits fall between these lengths.
+		{"abcde", 0},
+		{"abcdef", 1},
+		{"abcdefgh", 2},
+		{"abcdefghijklm", 3},
+		{"abcdefghijklmnopq", 3},
+		{"abcdefghijklmnopqr", 4},
+
+		// Length alone doesn't help when the characters repeat.
+		{"aaaaaaaaaaaaaaaaaaaa", 0},
+		{"abababababababababab", 0},
+
+		// Each class adds to the pool, so the same length scores higher.
+		{"86753091", 0},
+		{"aB3$", 0},
+		{"aB3$eF", 2},
+		{"aB3$eF7&jK", 3},
+		{"aB3$eF7&jK9(mN", 4},
+
+		// Blocklisted, whatever the case or the length.
+		{"password", 0},
+		{"Password", 0},
+		{"EverQuest", 0},
+		{"password123", 0},
+		{"qwertyuiop", 0},
+	}
+	for _, tt := range tests {
+		if got := scorePassword(tt.password); got != tt.want {
+			t.Errorf("scorePassword(%q) = %d (%s), want %d (%s)",
+				tt.password, got, strengthLabels[got], tt.want, strengthLabels[tt.want])
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// hashTemplate holds the salt and cost parameters of an existing Argon2 or
+// SCrypt hash, so a new password can be hashed with exactly the same inputs
+// and compared byte-for-byte with what the server produced.
+type hashTemplate struct {
+	Mode int // 13 or 14
+
+	Argon2 argon2Params
+	Salt   []byte // Argon2 raw salt
+	KeyLen uint32 // Argon2 hash length in bytes
+
+	SCrypt      scryptParams
+	EncodedSalt string // SCrypt salt exactly as it appears in the hash
+}
+
+// parseHashTemplate extracts the salt and parameters from a $argon2id$ or $7$
+// hash.
+func parseHashTemplate(s string) (hashTemplate, error) {
+	switch {
+	case strings.HasPrefix(s, "$argon2"):
+		params, salt, hash, err := parseArgon2PHC(s)
+		if err != nil {
+			return hashTemplate{}, err
+		}
+		if params.Memory > argon2MaxMemoryKiB {
+			return hashTemplate{}, fmt.Errorf("memory cost m=%d KiB exceeds the %d KiB limit", params.Memory, argon2MaxMemoryKiB)
+		}
+		return hashTemplate{Mode: 13, Argon2: params, Salt: salt, KeyLen: uint32(len(hash))}, nil
+
+	case strings.HasPrefix(s, "$7$"):
+		params, encodedSalt, _, err := parseSCryptMCF(s)
+		if err != nil {
+			return hashTemplate{}, err
+		}
+		if err := params.checkMemory(); err != nil {
+			return hashTemplate{}, err
+		}
+		return hashTemplate{Mode: 14, SCrypt: params, EncodedSalt: encodedSalt}, nil
+	}
+	return hashTemplate{}, fmt.Errorf("template must be a $argon2id$ or $7$ hash")
+}
+
+// hash hashes password with the template's salt and parameters.
+func (t hashTemplate) hash(password string) (string, error) {
+	if password == "" {
+		return "", errEmptyPassword
+	}
+	if err := checkPasswordLength(password); err != nil {
+		return "", err
+	}
+	var hash string
+	var err error
+	if t.Mode == 13 {
+		hash, err = hashArgon2WithParams(password, t.Salt, t.Argon2, t.KeyLen)
+	} else {
+		hash, err = hashSCryptWithParams(password, t.EncodedSalt, t.SCrypt)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := checkHashFormat(t.Mode, hash); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// costNote is kdfCostNote for the template's parameters.
+func (t hashTemplate) costNote() string {
+	return kdfCostNote(t.Mode, t.Argon2, t.SCrypt)
+}
+
+// kdfCostNote warns about mode 13 or 14 parameters that are below libsodium's
+// minimums, which hashing refuses, or cheaper than the loginserver's
+// INTERACTIVE defaults. It returns "" for parameters at least as strong.
+func kdfCostNote(mode int, argon argon2Params, scrypt scryptParams) string {
+	if mode == 13 {
+		if err := argon.checkMinimum(); err != nil {
+			return err.Error()
+		}
+		if argon.belowInteractive() {
+			return tr("kdf.weak", argon, argon2Interactive)
+		}
+		return ""
+	}
+	if err := scrypt.checkMinimum(); err != nil {
+		return err.Error()
+	}
+	if scrypt.belowInteractive() {
+		return tr("kdf.weak", scrypt, scryptInteractive)
+	}
+	return ""
+}
+
+func (t hashTemplate) String() string {
+	if t.Mode == 13 {
+		return tr("template.argon2", t.Argon2, len(t.Salt), t.KeyLen)
+	}
+	return tr("template.scrypt", t.SCrypt, t.EncodedSalt)
+}
+
+// rawSalt returns the template's salt bytes. SCrypt keeps the salt encoded
+// in the hash, so it is decoded; a salt that isn't a whole number of bytes
+// can still be used as a template but has no raw form.
+func (t hashTemplate) rawSalt() ([]byte, error) {
+	if t.Mode == 13 {
+		return t.Salt, nil
+	}
+	return decode64Bytes(t.EncodedSalt)
+}
+
+// describeSalt shows the salt of an Argon2 or SCrypt hash in hex and base64,
+// so its length and the escrypt encoding can be checked. It returns "" for
+// other hashes.
+func describeSalt(hash string) string {
+	t, err := parseHashTemplate(hash)
+	if err != nil {
+		return ""
+	}
+	raw, err := t.rawSalt()
+	if err != nil {
+		return tr("salt.error", err)
+	}
+	details := tr("salt.details", len(raw), hex.EncodeToString(raw), base64.StdEncoding.EncodeToString(raw))
+	if t.Mode == 14 {
+		details += "\n" + tr("salt.scrypt_encoded", t.EncodedSalt)
+	}
+	return details
+}
+
+// hashComponent is one part of an Argon2 or SCrypt hash, decoded for copying
+// on its own.
+type hashComponent struct {
+	Label string
+	Value string
+}
+
+// hashComponents splits a $argon2id$ or $7$ hash into its cost parameters,
+// salt and digest, with the salt and digest in hex, so each can be compared
+// with another tool's output separately. An SCrypt salt is also given as
+// encoded in the hash, since that text is what scrypt is actually given; a
+// salt that doesn't decode to whole bytes only has that form.
+func hashComponents(hash string) ([]hashComponent, error) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2"):
+		params, salt, key, err := parseArgon2PHC(hash)
+		if err != nil {
+			return nil, err
+		}
+		return []hashComponent{
+			{tr("component.params"), params.String()},
+			{tr("component.salt"), hex.EncodeToString(salt)},
+			{tr("component.digest"), hex.EncodeToString(key)},
+		}, nil
+
+	case strings.HasPrefix(hash, "$7$"):
+		params, encodedSalt, key, err := parseSCryptMCF(hash)
+		if err != nil {
+			return nil, err
+		}
+		components := []hashComponent{{tr("component.params"), params.String()}}
+		if salt, err := decode64Bytes(encodedSalt); err == nil {
+			components = append(components, hashComponent{tr("component.salt"), hex.EncodeToString(salt)})
+		}
+		return append(components,
+			hashComponent{tr("component.salt_encoded"), encodedSalt},
+			hashComponent{tr("component.digest"), hex.EncodeToString(key)},
+		), nil
+	}
+	return nil, fmt.Errorf("not an Argon2 or SCrypt hash")
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestHashTemplateRoundTrip(t *testing.T) {
+	for _, mode := range []int{13, 14} {
+		tmpl, err := parseHashTemplate(goldenVectors[mode])
+		if err != nil {
+			t.Fatalf("mode %d: %v", mode, err)
+		}
+		if tmpl.Mode != mode {
+			t.Errorf("mode %d: template has mode %d", mode, tmpl.Mode)
+		}
+		got, err := tmpl.hash(goldenPassword)
+		if err != nil {
+			t.Fatalf("mode %d: %v", mode, err)
+		}
+		if got != goldenVectors[mode] {
+			t.Errorf("mode %d:\n got  %s\n want %s", mode, got, goldenVectors[mode])
+		}
+	}
+}
+
+func TestHashTemplateErrors(t *testing.T) {
+	for _, bad := range []string{
+		"",
+		goldenVectors[1],
+		"$argon2i$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$aGFzaA",
+		"$7$C6..../....",
+	} {
+		if _, err := parseHashTemplate(bad); err == nil {
+			t.Errorf("parseHashTemplate(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestDescribeSalt(t *testing.T) {
+	salt := bytes.Repeat([]byte{0xab}, 32)
+	hash, err := hashSCryptWithSalt(goldenPassword, salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := describeSalt(hash)
+	for _, want := range []string{
+		"32 bytes",
+		strings.Repeat("ab", 32),
+		base64.StdEncoding.EncodeToString(salt),
+		encode64Bytes(salt),
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("SCrypt salt details missing %q:\n%s", want, got)
+		}
+	}
+
+	salt = salt[:16]
+	hash, err = hashArgon2WithSalt(goldenPassword, salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := describeSalt(hash); !strings.Contains(got, "16 bytes") || !strings.Contains(got, strings.Repeat("ab", 16)) {
+		t.Errorf("Argon2 salt details:\n%s", got)
+	}
+
+	if got := describeSalt(goldenVectors[5]); got != "" {
+		t.Errorf("SHA1 hash has salt details %q", got)
+	}
+}
+
+func TestHashComponents(t *testing.T) {
+	scryptSalt := goldenVectors[14][14:strings.LastIndex(goldenVectors[14], "$")]
+	for _, tc := range []struct {
+		mode int
+		want []string
+	}{
+		{13, []string{"m=65536, t=2, p=1", hex.EncodeToString(goldenSalt(16)),
+			"25260bb44c691032c04a50c86a7367b3d088a99431936eae6499cea4722719b6"}},
+		{14, []string{"N=16384, r=8, p=1", hex.EncodeToString(goldenSalt(32)), scryptSalt}},
+	} {
+		components, err := hashComponents(goldenVectors[tc.mode])
+		if err != nil {
+			t.Fatalf("mode %d: %v", tc.mode, err)
+		}
+		for i, want := range tc.want {
+			if components[i].Value != want {
+				t.Errorf("mode %d component %d (%s) = %q, want %q", tc.mode, i, components[i].Label, components[i].Value, want)
+			}
+		}
+		if digest := components[len(components)-1].Value; len(digest) != 64 {
+			t.Errorf("mode %d digest %q", tc.mode, digest)
+		}
+	}
+
+	for _, bad := range []string{goldenVectors[5], "$7$C6..../....", "$argon2id$v=19$m=65536"} {
+		if _, err := hashComponents(bad); err == nil {
+			t.Errorf("hashComponents(%q): no error", bad)
+		}
+	}
+}
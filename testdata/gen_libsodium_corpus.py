@@ -0,0 +1,71 @@
+#!/usr/bin/env python3
+"""Regenerates libsodium_corpus.json, the hashes libsodium_corpus_test.go
+verifies, by calling the system libsodium directly:
+
+    python3 testdata/gen_libsodium_corpus.py > testdata/libsodium_corpus.json
+
+Most entries use cheap cost parameters so the test stays fast; a few use the
+INTERACTIVE presets the loginserver uses.
+"""
+import ctypes
+import ctypes.util
+import json
+import random
+
+sodium = ctypes.CDLL(ctypes.util.find_library("sodium") or "libsodium.so.23")
+assert sodium.sodium_init() >= 0
+
+STRBYTES = 128
+ARGON2ID_INTERACTIVE = (2, 67108864)
+SCRYPT_INTERACTIVE = (524288, 16777216)
+
+
+def argon2id(password, ops, mem):
+    out = ctypes.create_string_buffer(STRBYTES)
+    pw = password.encode()
+    if sodium.crypto_pwhash_argon2id_str(out, pw, ctypes.c_ulonglong(len(pw)),
+                                         ctypes.c_ulonglong(ops), ctypes.c_size_t(mem)) != 0:
+        raise RuntimeError("crypto_pwhash_argon2id_str failed")
+    return out.value.decode()
+
+
+def scrypt(password, ops, mem):
+    out = ctypes.create_string_buffer(102)  # crypto_pwhash_scryptsalsa208sha256_STRBYTES
+    pw = password.encode()
+    if sodium.crypto_pwhash_scryptsalsa208sha256_str(out, pw, ctypes.c_ulonglong(len(pw)),
+                                                     ctypes.c_ulonglong(ops), ctypes.c_size_t(mem)) != 0:
+        raise RuntimeError("crypto_pwhash_scryptsalsa208sha256_str failed")
+    return out.value.decode()
+
+
+def passwords(n):
+    rng = random.Random(331)
+    fixed = [
+        "a", "password", "Kaladim#4Ever", "correct horse battery staple",
+        "tab\tinside", "quote'\"both", "back\\slash", "colon:user",
+        "ünïcödé", "пароль", "密码", "emoji \U0001F409", "x" * 256,
+    ]
+    alphabet = "".join(chr(c) for c in range(0x21, 0x7f)) + " éß"
+    out = list(fixed)
+    while len(out) < n:
+        out.append("".join(rng.choice(alphabet) for _ in range(rng.randint(1, 64))))
+    return out
+
+
+def main():
+    corpus = []
+    for i, password in enumerate(passwords(100)):
+        if i < 3:
+            corpus.append({"password": password, "hash": argon2id(password, *ARGON2ID_INTERACTIVE)})
+            corpus.append({"password": password, "hash": scrypt(password, *SCRYPT_INTERACTIVE)})
+            continue
+        # Vary t and m for Argon2; for scrypt, the minimum limits give
+        # N=2^10 and larger OPSLIMITs raise p.
+        corpus.append({"password": password, "hash": argon2id(password, 1 + i % 3, 8192 << (i % 4))})
+        corpus.append({"password": password, "hash": scrypt(password, 32768 << (i % 3), 1048576 * (1 + i % 2))})
+    json.dump(corpus, __import__("sys").stdout, indent=1, ensure_ascii=False)
+    print()
+
+
+if __name__ == "__main__":
+    main()
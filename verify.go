@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Hex digest length of each unsalted hash family, and the modes that use it.
+var hexModesByLength = map[int][]int{
+	32:  {1, 2, 3, 4},                     // MD5
+	40:  {5, 6, 7, 8},                     // SHA1
+	64:  {15, 16, 17, 18, 19, 20, 21, 22}, // SHA256, SHA3-256
+	128: {9, 10, 11, 12, 23, 24, 25, 26},  // SHA512, SHA3-512
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// cleanHash trims a pasted hash and removes any whitespace or control
+// characters left inside it, such as a CR from a Notepad copy. None of the
+// hash formats contain them, so they can only be clipboard junk that would
+// otherwise make the comparison fail silently. removed counts the characters
+// removed from inside the hash; surrounding whitespace isn't counted.
+func cleanHash(s string) (hash string, removed int) {
+	hash = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			removed++
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(s))
+	return hash, removed
+}
+
+// hashLines returns the non-blank lines of a dropped hash file, trimmed, so
+// CRLF files and trailing newlines don't end up in the hash entry.
+func hashLines(data []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// rowFields splits one line of a copied query result into fields. Tabs win
+// over commas, since that is what most SQL clients put on the clipboard.
+// Argon2's "m=..,t=..,p=.." parameters are rejoined after a comma split, and
+// surrounding quotes and whitespace are removed from each field.
+func rowFields(line string) []string {
+	sep := "\t"
+	if !strings.Contains(line, sep) {
+		sep = ","
+	}
+	parts := strings.Split(line, sep)
+
+	var fields []string
+	for i := 0; i < len(parts); i++ {
+		field := parts[i]
+		if sep == "," && strings.Contains(field, "$argon2") && i+2 < len(parts) &&
+			strings.HasPrefix(parts[i+1], "t=") && strings.HasPrefix(parts[i+2], "p=") {
+			field = strings.Join(parts[i:i+3], ",")
+			i += 2
+		}
+		field = strings.TrimSpace(field)
+		field = strings.Trim(field, "\"'`")
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// findHashField looks through pasted text, such as a login_accounts row
+// copied from a SQL client, for the field that looks most like a stored
+// hash: SCrypt or Argon2 first, then a hex digest. It returns the field and
+// its 1-based position among the fields of its line.
+func findHashField(text string) (hash string, field, fields int, ok bool) {
+	best := 0
+	for _, line := range hashLines([]byte(text)) {
+		row := rowFields(line)
+		for i, f := range row {
+			modes := detectHashModes(f)
+			if len(modes) == 0 {
+				continue
+			}
+			score := 1
+			if modes[0] == 13 || modes[0] == 14 {
+				score = 2
+			}
+			if score > best {
+				best, hash, field, fields = score, f, i+1, len(row)
+			}
+		}
+	}
+	return hash, field, fields, best > 0
+}
+
+// detectHashModes returns the modes a stored hash could have been produced
+// by, judging only from its format. The hex families can't be told apart any
+// further without trying each mode.
+func detectHashModes(hash string) []int {
+	switch {
+	case strings.HasPrefix(hash, "$7$"):
+		return []int{14}
+	case strings.HasPrefix(hash, "$argon2"):
+		return []int{13}
+	case isHex(hash):
+		return hexModesByLength[len(hash)]
+	}
+	return nil
+}
+
+// verifyHash checks a password against a stored hash for one mode. Hex
+// digests are compared case-insensitively in constant time.
+func verifyHash(storedHash, username, password string, mode int) (bool, error) {
+	if err := checkPasswordLength(password); err != nil {
+		return false, err
+	}
+	switch {
+	case mode == 13:
+		return verifyArgon2(storedHash, password)
+	case mode == 14:
+		return verifySCrypt(storedHash, password)
+	case tripleModes[mode]:
+		return verifyTriple(storedHash, username, password, mode)
+	}
+
+	computed, err := eqcryptHash(username, password, mode)
+	if err != nil {
+		return false, err
+	}
+	stored := []byte(strings.ToLower(storedHash))
+	return subtle.ConstantTimeCompare(stored, []byte(computed)) == 1, nil
+}
+
+// Modes that hash the digests of the username and password, e.g. mode 4,
+// md5(md5(username) + md5(password)).
+var tripleModes = modeSet(func(m modeInfo) bool { return strings.Contains(m.Label, "Triple") })
+
+// tripleDigests are the digests the triple modes are built on, by family.
+var tripleDigests = map[string]func(string) string{
+	"md5": hashMD5, "sha1": hashSHA1, "sha256": hashSHA256, "sha512": hashSHA512,
+	"sha3-256": hashSHA3_256, "sha3-512": hashSHA3_512,
+}
+
+// verifyTriple checks a triple mode hash on its own rather than through
+// eqcryptHash, since the nesting is easy to get subtly wrong: the username
+// and password digests are concatenated as lowercase hex, username first and
+// with no separator, and that string is digested again.
+func verifyTriple(storedHash, username, password string, mode int) (bool, error) {
+	m, ok := lookupMode(mode)
+	if !ok || !tripleModes[mode] {
+		return false, fmt.Errorf("mode %d is not a triple hash mode", mode)
+	}
+	if username == "" {
+		return false, fmt.Errorf("mode %d needs the account name", mode)
+	}
+	digest := tripleDigests[m.family()]
+	computed := digest(digest(username) + digest(password))
+	stored := []byte(strings.ToLower(storedHash))
+	return subtle.ConstantTimeCompare(stored, []byte(computed)) == 1, nil
+}
+
+// compareHashes reports whether two stored hashes are identical, ignoring
+// surrounding whitespace, in constant time. With ignoreHexCase, two hex
+// digests are compared case-insensitively, since tools differ on the case of
+// MD5/SHA output; the base64 in Argon2 and SCrypt hashes is case-sensitive
+// and always compared as is.
+func compareHashes(a, b string, ignoreHexCase bool) bool {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	if ignoreHexCase && isHex(a) && isHex(b) {
+		a, b = strings.ToLower(a), strings.ToLower(b)
+	}
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// verifyAnyMode tries every mode that fits the stored hash's format and
+// returns the first that matches, or 0 if none do. Modes that need a username
+// are skipped when username is empty.
+func verifyAnyMode(storedHash, username, password string) (int, error) {
+	return verifySelected(storedHash, username, password, 0)
+}
+
+// verifySelected is verifyAnyMode with the selected mode tried first, so a
+// wrong mode can be told apart from a wrong password: if the password matches
+// under another mode that fits, that mode is returned instead. selected is
+// skipped if the hash isn't in its format, and 0 selects nothing.
+func verifySelected(storedHash, username, password string, selected int) (int, error) {
+	candidates := detectHashModes(storedHash)
+	if len(candidates) == 0 {
+		return 0, fmt.Errorf("unrecognized hash format (%d chars)", len(storedHash))
+	}
+	if i := slices.Index(candidates, selected); i > 0 {
+		candidates = append([]int{selected}, slices.Delete(slices.Clone(candidates), i, i+1)...)
+	}
+
+	for _, mode := range candidates {
+		if modeNeedsUsername[mode] && username == "" {
+			continue
+		}
+		ok, err := verifyHash(storedHash, username, password, mode)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return mode, nil
+		}
+	}
+	return 0, nil
+}
+
+// verifyVerdict checks password against storedHash with the given mode
+// selected and returns the Verify tab's PASS/FAIL line for the result. The
+// Generate tab's Check button uses it with that tab's inputs.
+func verifyVerdict(storedHash, username, password string, selected int) (string, error) {
+	matched, err := verifySelected(storedHash, username, password, selected)
+	if err != nil {
+		return "", err
+	}
+	candidates := detectHashModes(storedHash)
+	switch {
+	case matched != 0 && matched != selected:
+		return tr("verify.pass_other_mode", matched, modeName(matched), selected), nil
+	case matched != 0:
+		return tr("verify.pass", matched, modeName(matched)), nil
+	case !slices.Contains(candidates, selected):
+		return tr("verify.fail_wrong_format", selected, modeList(candidates)), nil
+	}
+	return tr("verify.fail", modeList(candidates)), nil
+}
+
+// skippedModes returns the modes that fit the stored hash's format but need
+// a username, which verifyAnyMode skips when none is given.
+func skippedModes(storedHash, username string) []int {
+	if username != "" {
+		return nil
+	}
+	var skipped []int
+	for _, mode := range detectHashModes(storedHash) {
+		if modeNeedsUsername[mode] {
+			skipped = append(skipped, mode)
+		}
+	}
+	return skipped
+}
+
+// modeList formats mode numbers for messages, e.g. "2, 3, 4".
+func modeList(modes []int) string {
+	numbers := make([]string, len(modes))
+	for i, mode := range modes {
+		numbers[i] = strconv.Itoa(mode)
+	}
+	return strings.Join(numbers, ", ")
+}
+
+// hexTypoMax is the most differing characters a failed hex verification can
+// have and still be reported as a likely transcription typo.
+const hexTypoMax = 2
+
+// hexDiff is how a stored hex hash differs from the one computed for the
+// entered password under Mode.
+type hexDiff struct {
+	Mode     int
+	Computed string
+	First    int // index of the first differing character
+	Count    int // number of differing characters
+}
+
+// closestHexMode computes the password's hash under each of modes and
+// returns the one with the fewest characters differing from the stored hex
+// hash, so a failed verification can show where it went wrong. Case is
+// ignored, as in verifyHash. ok is false for a hash that isn't hex or if no
+// mode could be compared.
+func closestHexMode(storedHash, username, password string, modes []int) (diff hexDiff, ok bool) {
+	if !isHex(storedHash) {
+		return hexDiff{}, false
+	}
+	stored := strings.ToLower(storedHash)
+	for _, mode := range modes {
+		if modeNeedsUsername[mode] && username == "" {
+			continue
+		}
+		computed, err := eqcryptHash(username, password, mode)
+		if err != nil || len(computed) != len(stored) {
+			continue
+		}
+		d := hexDiff{Mode: mode, Computed: computed, First: -1}
+		for i := range stored {
+			if stored[i] != computed[i] {
+				if d.First < 0 {
+					d.First = i
+				}
+				d.Count++
+			}
+		}
+		if !ok || d.Count < diff.Count {
+			diff, ok = d, true
+		}
+	}
+	return diff, ok
+}
+
+// diffRun is a stretch of a hash whose characters either all match or all
+// differ from the hash it's compared with.
+type diffRun struct {
+	Text   string
+	Differ bool
+}
+
+// diffRuns splits a into runs by whether each character matches the one at
+// the same position in b, ignoring case. Characters past the end of b differ.
+func diffRuns(a, b string) []diffRun {
+	var runs []diffRun
+	for i := 0; i < len(a); i++ {
+		differ := i >= len(b) || !strings.EqualFold(a[i:i+1], b[i:i+1])
+		if len(runs) == 0 || runs[len(runs)-1].Differ != differ {
+			runs = append(runs, diffRun{Differ: differ})
+		}
+		runs[len(runs)-1].Text += a[i : i+1]
+	}
+	return runs
+}
+
+// candidatePasswords splits a multi-line list of passwords, one per line.
+// Only line endings are removed, since spaces may be part of a password;
+// blank lines are skipped.
+func candidatePasswords(text string) []string {
+	var candidates []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSuffix(line, "\r"); line != "" {
+			candidates = append(candidates, line)
+		}
+	}
+	return candidates
+}
+
+// findPassword tries each candidate against the stored hash in turn and
+// returns the index of the first that matches and its mode, or -1 if none
+// do. Argon2 and SCrypt take a noticeable time per candidate, so ctx is
+// checked between candidates and progress is called after each one.
+func findPassword(ctx context.Context, storedHash, username string, candidates []string, progress func(done int)) (int, int, error) {
+	for i, candidate := range candidates {
+		if err := ctx.Err(); err != nil {
+			return -1, 0, err
+		}
+		mode, err := verifyAnyMode(storedHash, username, candidate)
+		if err != nil {
+			return -1, 0, err
+		}
+		if progress != nil {
+			progress(i + 1)
+		}
+		if mode != 0 {
+			return i, mode, nil
+		}
+	}
+	return -1, 0, nil
+}
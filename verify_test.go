@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"golang.org/x/crypto/scrypt"
@@ -67,13 +70,21 @@ func TestSCryptVector(t *testing.T) {
 
 func TestSCryptVerify(t *testing.T) {
 	password := "Yawgmoth69!!??"
+	verified := func(hash, password string) bool {
+		t.Helper()
+		ok, err := verifySCrypt(hash, password)
+		if err != nil {
+			t.Fatalf("%s: %v", hash, err)
+		}
+		return ok
+	}
 
 	serverHash := "$7$C6..../....o6qKd2HVUARWTdHViztsqQ.eGYS8Vi7jwD6jijrJtrC$CAyWIxCQRHRgYzqyj/6mG9u6kuyQURTT7R9hoeNrg90"
 	appHash := "$7$C6..../....on6C5csxdh5qCyNitycuPn1i6R/sGmYN0oZ86Io3yy/$8I/Hr.E.m785FbRYIYP4VQrN9tEdSNZvSqLrUahOuk1"
 
-	fmt.Printf("Verify server hash: %v\n", verifySCrypt(serverHash, password))
-	fmt.Printf("Verify app hash:    %v\n", verifySCrypt(appHash, password))
-	fmt.Printf("Verify wrong pass:  %v\n", verifySCrypt(appHash, "wrongpassword"))
+	fmt.Printf("Verify server hash: %v\n", verified(serverHash, password))
+	fmt.Printf("Verify app hash:    %v\n", verified(appHash, password))
+	fmt.Printf("Verify wrong pass:  %v\n", verified(appHash, "wrongpassword"))
 
 	// Generate a new hash with our app and immediately verify it
 	newHash, err := hashSCrypt(password)
@@ -81,51 +92,285 @@ func TestSCryptVerify(t *testing.T) {
 		t.Fatal(err)
 	}
 	fmt.Printf("\nNewly generated hash: %s\n", newHash)
-	fmt.Printf("Verify new hash:      %v\n", verifySCrypt(newHash, password))
+	fmt.Printf("Verify new hash:      %v\n", verified(newHash, password))
 	fmt.Printf("Hash length:          %d\n", len(newHash))
 
-	if !verifySCrypt(serverHash, password) {
+	if !verified(serverHash, password) {
 		t.Error("Server hash failed verification")
 	}
-	if !verifySCrypt(appHash, password) {
+	if !verified(appHash, password) {
 		t.Error("App hash failed verification")
 	}
-	if !verifySCrypt(newHash, password) {
+	if !verified(newHash, password) {
 		t.Error("Newly generated hash failed verification")
 	}
-	if verifySCrypt(newHash, "wrongpassword") {
+	if verified(newHash, "wrongpassword") {
 		t.Error("Wrong password should not verify")
 	}
 }
 
-// decode64Bytes decodes custom base64 back to raw bytes
-func decode64Bytes(src string) []byte {
-	var atoi64 [256]int
-	for i := range atoi64 {
-		atoi64[i] = -1
-	}
-	for i, c := range itoa64 {
-		atoi64[c] = i
-	}
-
-	var result []byte
-	i := 0
-	for i < len(src) {
-		var value uint32
-		var bits int
-		for bits < 24 && i < len(src) {
-			c := atoi64[src[i]]
-			if c < 0 {
-				break
+func TestVerifyArgon2(t *testing.T) {
+	// Produced by libsodium's crypto_pwhash_argon2id_str with a random salt
+	libsodiumHash := "$argon2id$v=19$m=65536,t=2,p=1$5wqbso/fUHCmbUFNg1/E0w$y60EYczuy1HcqBr7cTVP22+JH7eUC+DBvSb0Bs4Rmkc"
+
+	for _, hash := range []string{goldenVectors[13], libsodiumHash} {
+		ok, err := verifyArgon2(hash, goldenPassword)
+		if err != nil || !ok {
+			t.Errorf("%s: got %v, %v, want a match", hash, ok, err)
+		}
+		ok, err = verifyArgon2(hash, "wrongpassword")
+		if err != nil || ok {
+			t.Errorf("%s: wrong password got %v, %v", hash, ok, err)
+		}
+	}
+
+	if _, err := verifyArgon2("$argon2id$v=19$m=4294967295,t=2,p=1$AAECAwQFBgcICQoLDA0ODw$JSYLtExpEDLASlDIanNns9CIqZQxk26uZJnOpHInGbY", goldenPassword); err == nil {
+		t.Error("expected an absurd memory cost to be refused")
+	}
+}
+
+func TestVerifyAnyMode(t *testing.T) {
+	for mode, hash := range goldenVectors {
+		got, err := verifyAnyMode(hash, goldenUsername, goldenPassword)
+		if err != nil || got != mode {
+			t.Errorf("mode %d: verifyAnyMode = %d, %v", mode, got, err)
+		}
+		if got, _ := verifyAnyMode(strings.ToUpper(hash), goldenUsername, goldenPassword); mode <= 12 && got != mode {
+			t.Errorf("mode %d: uppercase hex digest not matched", mode)
+		}
+	}
+
+	if got, err := verifyAnyMode(goldenVectors[2], "", goldenPassword); err != nil || got != 0 {
+		t.Errorf("mode 2 without username: got %d, %v, want no match", got, err)
+	}
+	if _, err := verifyAnyMode("not a hash", goldenUsername, goldenPassword); err == nil {
+		t.Error("expected an error for an unrecognized hash")
+	}
+}
+
+func TestFindPassword(t *testing.T) {
+	candidates := candidatePasswords("hunter2\r\n\n" + goldenPassword + "\nnever tried\n")
+	if len(candidates) != 3 {
+		t.Fatalf("got candidates %q", candidates)
+	}
+
+	var done int
+	i, mode, err := findPassword(context.Background(), goldenVectors[14], "", candidates, func(n int) { done = n })
+	if err != nil || i != 1 || mode != 14 {
+		t.Errorf("got index %d, mode %d, %v; want index 1, mode 14", i, mode, err)
+	}
+	if done != 2 {
+		t.Errorf("progress reported %d candidates, want 2 (stops at the match)", done)
+	}
+
+	if i, _, err := findPassword(context.Background(), goldenVectors[1], "", []string{"a", "b"}, nil); err != nil || i != -1 {
+		t.Errorf("no match: got index %d, %v", i, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := findPassword(ctx, goldenVectors[14], "", candidates, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled: got %v", err)
+	}
+}
+
+func TestFindHashField(t *testing.T) {
+	argon2Hash := goldenVectors[13]
+	sha1Hash := goldenVectors[5]
+
+	tests := []struct {
+		name   string
+		text   string
+		hash   string
+		field  int
+		fields int
+	}{
+		{"tab row", "7\tGearheart\t" + argon2Hash + "\tlocal\t2024-01-01 00:00:00", argon2Hash, 3, 5},
+		{"csv row with unquoted argon2", "7,Gearheart," + argon2Hash + ",local", argon2Hash, 3, 4},
+		{"quoted csv row", `"7","Gearheart","` + sha1Hash + `","local"`, sha1Hash, 3, 4},
+		{"header and row", "id\taccount_name\taccount_password\n7\tGearheart\t" + sha1Hash, sha1Hash, 3, 3},
+		{"kdf beats hex", sha1Hash + "\t" + argon2Hash, argon2Hash, 2, 2},
+	}
+	for _, tt := range tests {
+		hash, field, fields, ok := findHashField(tt.text)
+		if !ok || hash != tt.hash || field != tt.field || fields != tt.fields {
+			t.Errorf("%s: got %q, field %d of %d, %v; want %q, field %d of %d",
+				tt.name, hash, field, fields, ok, tt.hash, tt.field, tt.fields)
+		}
+	}
+
+	if _, _, _, ok := findHashField("7\tGearheart\tlocal"); ok {
+		t.Error("found a hash in a row without one")
+	}
+}
+
+func TestCleanHash(t *testing.T) {
+	hash := goldenVectors[14]
+	tests := []struct {
+		in      string
+		removed int
+	}{
+		{hash, 0},
+		{" " + hash + "\r\n", 0},
+		{hash[:20] + "\r" + hash[20:] + "\r\n", 1},
+		{hash[:10] + "\t \u00a0" + hash[10:40] + "\x00" + hash[40:], 4},
+	}
+	for _, tt := range tests {
+		got, removed := cleanHash(tt.in)
+		if got != hash || removed != tt.removed {
+			t.Errorf("cleanHash(%q) = %q, %d, want %d removed", tt.in, got, removed, tt.removed)
+		}
+	}
+}
+
+func TestCompareHashes(t *testing.T) {
+	sha1Hash := goldenVectors[5]
+	upper := strings.ToUpper(sha1Hash)
+
+	if !compareHashes(sha1Hash, " "+sha1Hash+"\n", false) {
+		t.Error("surrounding whitespace not ignored")
+	}
+	if compareHashes(sha1Hash, upper, false) {
+		t.Error("hex case ignored without ignoreHexCase")
+	}
+	if !compareHashes(sha1Hash, upper, true) {
+		t.Error("hex case not ignored with ignoreHexCase")
+	}
+
+	// Base64 is case-sensitive, so only hex is ever case-folded.
+	argon2Hash := goldenVectors[13]
+	if compareHashes(argon2Hash, strings.ToUpper(argon2Hash), true) {
+		t.Error("Argon2 hash compared case-insensitively")
+	}
+	if compareHashes(sha1Hash, sha1Hash[:len(sha1Hash)-1], true) {
+		t.Error("hashes of different length compared equal")
+	}
+}
+
+func TestVerifySelected(t *testing.T) {
+	// The golden mode 7 hash checked with mode 6 selected: same format,
+	// so the password is reported as matching under mode 7 instead.
+	if got, err := verifySelected(goldenVectors[7], goldenUsername, goldenPassword, 6); err != nil || got != 7 {
+		t.Errorf("wrong mode selected: got %d, %v, want 7", got, err)
+	}
+	if got, err := verifySelected(goldenVectors[7], goldenUsername, goldenPassword, 7); err != nil || got != 7 {
+		t.Errorf("right mode selected: got %d, %v, want 7", got, err)
+	}
+	// A mode whose format doesn't fit is skipped rather than an error.
+	if got, err := verifySelected(goldenVectors[7], goldenUsername, goldenPassword, 14); err != nil || got != 7 {
+		t.Errorf("mode of another format selected: got %d, %v, want 7", got, err)
+	}
+	if got, err := verifySelected(goldenVectors[7], goldenUsername, "wrong", 7); err != nil || got != 0 {
+		t.Errorf("wrong password: got %d, %v, want no match", got, err)
+	}
+
+	if got := skippedModes(goldenVectors[5], ""); modeList(got) != "6, 7, 8" {
+		t.Errorf("skippedModes without username = %v", got)
+	}
+	if got := skippedModes(goldenVectors[5], goldenUsername); got != nil {
+		t.Errorf("skippedModes with username = %v", got)
+	}
+}
+
+func TestVerifyVerdict(t *testing.T) {
+	for _, c := range []struct {
+		hash, password string
+		mode           int
+		want           string
+	}{
+		{goldenVectors[7], goldenPassword, 7, "PASS - Password matches under mode 7"},
+		{goldenVectors[7], goldenPassword, 6, "Password matches, but under mode 7"},
+		{goldenVectors[7], "wrong", 7, "FAIL - Password does not match"},
+		{goldenVectors[7], "wrong", 14, "FAIL - The hash is not in mode 14's format"},
+	} {
+		got, err := verifyVerdict(c.hash, goldenUsername, c.password, c.mode)
+		if err != nil || !strings.HasPrefix(got, c.want) {
+			t.Errorf("mode %d, password %q: got %q, %v, want %q...", c.mode, c.password, got, err, c.want)
+		}
+	}
+	if _, err := verifyVerdict("not a hash", goldenUsername, goldenPassword, 1); err == nil {
+		t.Error("unrecognized hash: no error")
+	}
+}
+
+func TestClosestHexMode(t *testing.T) {
+	stored := goldenVectors[5]
+	typo := []byte(strings.ToUpper(stored))
+	typo[7] ^= 1 // a hex digit off by one bit is still a hex digit
+	diff, ok := closestHexMode(string(typo), goldenUsername, goldenPassword, detectHashModes(stored))
+	if !ok || diff.Mode != 5 || diff.Count != 1 || diff.First != 7 || diff.Computed != stored {
+		t.Errorf("one-character typo: %+v, %v", diff, ok)
+	}
+
+	diff, ok = closestHexMode(stored, goldenUsername, "wrong", []int{5})
+	if !ok || diff.Count <= hexTypoMax {
+		t.Errorf("wrong password: %+v, %v, want many differences", diff, ok)
+	}
+
+	if _, ok := closestHexMode(goldenVectors[14], "", goldenPassword, []int{14}); ok {
+		t.Error("SCrypt hash compared as hex")
+	}
+}
+
+func TestDiffRuns(t *testing.T) {
+	got := diffRuns("abCDef12", "ABxdeF")
+	want := []diffRun{{"ab", false}, {"C", true}, {"Def", false}, {"12", true}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("diffRuns = %v, want %v", got, want)
+	}
+	if runs := diffRuns("", "ab"); len(runs) != 0 {
+		t.Errorf("empty hash: %v", runs)
+	}
+}
+
+func TestVerifyTriple(t *testing.T) {
+	// Computed independently with Python's hashlib as
+	// h(h(username).hexdigest() + h(password).hexdigest()).hexdigest().
+	const username, password = "Firiona", "Vie@Kael2"
+	vectors := map[int]string{
+		4:  "9b820eb2296a64565cdd4ce49ca72021",
+		8:  "a5339fc6e616cc754554e1dc4d24d27552f3d1b9",
+		12: "ed85fa8120b7c634972d55447ec9b0450e3fc7895b773cb928f1bd6322d0ca486cb93fc5da0d887158ab30abc09e841c677116cc670daf3cb36ab07bb9b469f1",
+		18: "4fc593e146b547d5de2d0159969c3b381fdca86f763a7f2fdecf569450986e59",
+		22: "f5e62bb4c843cb3cb9d8c02517534ddfc268fa3d2a5b66f2316f7ff67b60ffab",
+		26: "30bc6f68f8891025e8358225a1844b7c90a56491fd4440c831815a56c915b2ee9b51578978de846cf33539688cc5e9d412e8bab3a055a137f86406f0ae07e51b",
+	}
+	if len(vectors) != len(tripleModes) {
+		t.Fatalf("%d vectors for %d triple modes", len(vectors), len(tripleModes))
+	}
+	for mode, want := range vectors {
+		if ok, err := verifyTriple(strings.ToUpper(want), username, password, mode); !ok || err != nil {
+			t.Errorf("mode %d: %v, %v", mode, ok, err)
+		}
+		if got, _ := eqcryptHash(username, password, mode); got != want {
+			t.Errorf("mode %d generates %s, want %s", mode, got, want)
+		}
+
+		// Plausible mistakes in the nesting must not verify.
+		digest := tripleDigests[modeTable[mode-1].family()]
+		for name, wrong := range map[string]string{
+			"swapped":        digest(digest(password) + digest(username)),
+			"uppercase hex":  digest(strings.ToUpper(digest(username) + digest(password))),
+			"separator":      digest(digest(username) + ":" + digest(password)),
+			"not nested":     digest(username + password),
+			"wrong username": digest(digest(strings.ToLower(username)) + digest(password)),
+		} {
+			if ok, _ := verifyTriple(wrong, username, password, mode); ok {
+				t.Errorf("mode %d: %s hash verified", mode, name)
 			}
-			value |= uint32(c) << bits
-			bits += 6
-			i++
 		}
-		for b := 0; b < bits/8; b++ {
-			result = append(result, byte(value&0xff))
-			value >>= 8
+		if ok, _ := verifyTriple(want, username, "vie@kael2", mode); ok {
+			t.Errorf("mode %d: wrong password verified", mode)
 		}
 	}
-	return result
+
+	if _, err := verifyTriple(vectors[4], "", password, 4); err == nil {
+		t.Error("no error without a username")
+	}
+	if _, err := verifyTriple(goldenVectors[2], goldenUsername, goldenPassword, 2); err == nil {
+		t.Error("mode 2 accepted as a triple mode")
+	}
+	if mode, err := verifySelected(vectors[8], username, password, 8); mode != 8 || err != nil {
+		t.Errorf("verifySelected: mode %d, %v", mode, err)
+	}
 }